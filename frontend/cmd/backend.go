@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/uuid"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/backend"
+	"github.com/Azure/ARO-HCP/frontend/pkg/config"
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+)
+
+type BackendOpts struct {
+	clustersServiceURL string
+	insecure           bool
+
+	cosmosName string
+	cosmosURL  string
+}
+
+func NewBackendCmd() *cobra.Command {
+	opts := &BackendOpts{}
+	backendCmd := &cobra.Command{
+		Use:   "backend",
+		Args:  cobra.NoArgs,
+		Short: "Run the ARO HCP Backend",
+		Long: `Run the ARO HCP Backend
+
+	This command polls Clusters Service for the status of asynchronous operations
+	started by the frontend and records their progress in CosmosDB. Replicas
+	coordinate through a lease so only one reconciles at a time.
+
+	# Run ARO HCP Backend locally to connect to a local Clusters Service at http://localhost:8000
+	./aro-hcp-frontend backend --cosmos-name ${DB_NAME} --cosmos-url ${DB_URL} \
+		--clusters-service-url "http://localhost:8000"
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.Run()
+		},
+	}
+
+	backendCmd.Flags().StringVar(&opts.cosmosName, "cosmos-name", os.Getenv("DB_NAME"), "Cosmos database name")
+	backendCmd.Flags().StringVar(&opts.cosmosURL, "cosmos-url", os.Getenv("DB_URL"), "Cosmos database url")
+
+	backendCmd.Flags().StringVar(&opts.clustersServiceURL, "clusters-service-url", "https://api.openshift.com", "URL of the OCM API gateway.")
+	backendCmd.Flags().BoolVar(&opts.insecure, "insecure", false, "Skip validating TLS for clusters-service.")
+
+	backendCmd.MarkFlagsRequiredTogether("cosmos-name", "cosmos-url")
+
+	return backendCmd
+}
+
+func (opts *BackendOpts) Run() error {
+	logger := config.DefaultLogger()
+	logger.Info(fmt.Sprintf("backend (%s) started", version()))
+
+	dbConfig := database.NewCosmosDBConfig(opts.cosmosName, opts.cosmosURL)
+	dbClient, err := database.NewCosmosDBClient(dbConfig)
+	if err != nil {
+		return fmt.Errorf("creating the database client failed: %v", err)
+	}
+
+	conn, err := sdk.NewUnauthenticatedConnectionBuilder().
+		URL(opts.clustersServiceURL).
+		Insecure(opts.insecure).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	// Identify this replica uniquely, even across restarts in the same pod.
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	holder := fmt.Sprintf("%s-%s", hostname, uuid.New().String())
+
+	b := backend.NewBackend(logger, dbClient, ocm.NewClusterServiceClient(conn), holder)
+
+	stop := make(chan struct{})
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGINT, syscall.SIGTERM)
+	go b.Run(context.Background(), stop)
+
+	sig := <-signalChannel
+	logger.Info(fmt.Sprintf("caught %s signal", sig))
+	close(stop)
+
+	b.Join()
+	logger.Info(fmt.Sprintf("backend (%s) stopped", version()))
+
+	return nil
+}
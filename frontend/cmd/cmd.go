@@ -16,6 +16,7 @@ import (
 	"github.com/Azure/ARO-HCP/frontend/pkg/config"
 	"github.com/Azure/ARO-HCP/frontend/pkg/database"
 	"github.com/Azure/ARO-HCP/frontend/pkg/frontend"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
 	"github.com/Azure/ARO-HCP/internal/api"
 )
 
@@ -30,6 +31,7 @@ type FrontendOpts struct {
 	port   int
 
 	useCache   bool
+	cacheFile  string
 	cosmosName string
 	cosmosURL  string
 }
@@ -55,6 +57,7 @@ func NewRootCmd() *cobra.Command {
 	}
 
 	rootCmd.Flags().BoolVar(&opts.useCache, "use-cache", false, "leverage a local cache instead of reaching out to a database")
+	rootCmd.Flags().StringVar(&opts.cacheFile, "cache-file", "", "JSON file to persist the local cache to across restarts")
 	rootCmd.Flags().StringVar(&opts.cosmosName, "cosmos-name", os.Getenv("DB_NAME"), "Cosmos database name")
 	rootCmd.Flags().StringVar(&opts.cosmosURL, "cosmos-url", os.Getenv("DB_URL"), "Cosmos database url")
 	rootCmd.Flags().StringVar(&opts.region, "region", os.Getenv("REGION"), "Azure region")
@@ -69,6 +72,10 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.MarkFlagsMutuallyExclusive("use-cache", "cosmos-name")
 	rootCmd.MarkFlagsMutuallyExclusive("use-cache", "cosmos-url")
 	rootCmd.MarkFlagsRequiredTogether("cosmos-name", "cosmos-url")
+	rootCmd.MarkFlagsMutuallyExclusive("cache-file", "cosmos-name")
+	rootCmd.MarkFlagsMutuallyExclusive("cache-file", "cosmos-url")
+
+	rootCmd.AddCommand(NewBackendCmd())
 
 	return rootCmd
 }
@@ -81,8 +88,17 @@ func (opts *FrontendOpts) Run() error {
 	prometheusEmitter := frontend.NewPrometheusEmitter()
 
 	// Configure database configuration and client
-	dbClient := database.NewCache()
-	if !opts.useCache {
+	var dbClient database.DBClient
+	if opts.cacheFile != "" {
+		var err error
+
+		dbClient, err = database.NewPersistentCache(opts.cacheFile)
+		if err != nil {
+			return fmt.Errorf("loading the cache file failed: %v", err)
+		}
+	} else if opts.useCache {
+		dbClient = database.NewCache()
+	} else {
 		var err error
 
 		dbConfig := database.NewCosmosDBConfig(opts.cosmosName, opts.cosmosURL)
@@ -107,7 +123,6 @@ func (opts *FrontendOpts) Run() error {
 	}
 
 	csCfg := frontend.ClusterServiceConfig{
-		Conn:                       conn,
 		ProvisionerNoOpProvision:   opts.clusterServiceNoopDeprovision,
 		ProvisionerNoOpDeprovision: opts.clusterServiceNoopDeprovision,
 	}
@@ -121,7 +136,7 @@ func (opts *FrontendOpts) Run() error {
 	}
 	logger.Info(fmt.Sprintf("Application running in region: %s", opts.region))
 
-	f := frontend.NewFrontend(logger, listener, prometheusEmitter, dbClient, opts.region, csCfg)
+	f := frontend.NewFrontend(logger, listener, prometheusEmitter, dbClient, opts.region, ocm.NewClusterServiceClient(conn), csCfg)
 
 	stop := make(chan struct{})
 	signalChannel := make(chan os.Signal, 1)
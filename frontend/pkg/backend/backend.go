@@ -0,0 +1,389 @@
+package backend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/frontend"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	// LeaseName names the lease that elects one backend replica to
+	// reconcile operations at a time.
+	LeaseName = "backend"
+
+	DefaultPollInterval  = 10 * time.Second
+	DefaultLeaseDuration = 30 * time.Second
+)
+
+// Backend drives asynchronous operations started by the frontend to a
+// terminal state by polling Cluster Service for the resources they refer
+// to. Any number of replicas may run; only the lease holder does work.
+type Backend struct {
+	logger        *slog.Logger
+	dbClient      database.DBClient
+	csClient      ocm.ClusterServiceClient
+	holder        string
+	pollInterval  time.Duration
+	leaseDuration time.Duration
+	done          chan struct{}
+}
+
+func NewBackend(logger *slog.Logger, dbClient database.DBClient, csClient ocm.ClusterServiceClient, holder string) *Backend {
+	return &Backend{
+		logger:        logger,
+		dbClient:      dbClient,
+		csClient:      csClient,
+		holder:        holder,
+		pollInterval:  DefaultPollInterval,
+		leaseDuration: DefaultLeaseDuration,
+		done:          make(chan struct{}),
+	}
+}
+
+func (b *Backend) Run(ctx context.Context, stop <-chan struct{}) {
+	b.logger.Info(fmt.Sprintf("polling every %s as %s", b.pollInterval, b.holder))
+
+	ticker := time.NewTicker(b.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		b.poll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			if err := b.dbClient.ReleaseLease(ctx, LeaseName, b.holder); err != nil {
+				b.logger.Error(fmt.Sprintf("failed to release lease: %v", err))
+			}
+			close(b.done)
+			return
+		}
+	}
+}
+
+func (b *Backend) Join() {
+	<-b.done
+}
+
+// poll reconciles all in-flight operations if this replica holds the
+// lease. Acquiring the lease also renews it. The pass is bounded to half
+// the lease duration so it ends while the lease is still held, before
+// another replica could take over and reconcile the same operations.
+// Operations not reached in time are reconciled by the next pass.
+func (b *Backend) poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, b.leaseDuration/2)
+	defer cancel()
+
+	acquired, err := b.dbClient.AcquireLease(ctx, LeaseName, b.holder, b.leaseDuration)
+	if err != nil {
+		b.logger.Error(fmt.Sprintf("failed to acquire lease: %v", err))
+		return
+	}
+	if !acquired {
+		b.logger.Debug("lease held by another replica")
+		return
+	}
+
+	docs, err := b.dbClient.ListActiveOperationDocs(ctx)
+	if err != nil {
+		b.logger.Error(fmt.Sprintf("failed to list active operations: %v", err))
+		return
+	}
+
+	for i, doc := range docs {
+		if ctx.Err() != nil {
+			b.logger.Warn(fmt.Sprintf("deferring %d operations to the next poll: %v", len(docs)-i, ctx.Err()))
+			return
+		}
+		if err := b.reconcileOperation(ctx, doc); err != nil {
+			b.logger.Error(fmt.Sprintf("failed to reconcile operation %s: %v", doc.ID, err))
+		}
+	}
+}
+
+func (b *Backend) reconcileOperation(ctx context.Context, doc *database.OperationDocument) error {
+	switch doc.Request {
+	case database.OperationRequestWarn, database.OperationRequestSuspend, database.OperationRequestReinstate:
+		return b.reconcileSubscriptionOperation(ctx, doc)
+	}
+
+	if doc.InternalID == "" {
+		// Nothing to poll for in Cluster Service.
+		return nil
+	}
+
+	if frontend.IsNodePoolResourceID(doc.ExternalID) {
+		return b.reconcileNodePoolOperation(ctx, doc)
+	}
+	return b.reconcileClusterOperation(ctx, doc)
+}
+
+func (b *Backend) reconcileClusterOperation(ctx context.Context, doc *database.OperationDocument) error {
+	cluster, err := b.csClient.GetCSCluster(ctx, doc.InternalID)
+	if ocm.IsNotFound(err) {
+		if doc.Request != database.OperationRequestDelete {
+			return b.updateOperation(ctx, doc, arm.ProvisioningStateFailed, &arm.CloudErrorBody{
+				Code:    arm.CloudErrorCodeInternalServerError,
+				Message: "The cluster no longer exists.",
+			})
+		}
+		// A cluster that never became ready has no billing document.
+		err = b.dbClient.MarkBillingDocDeleted(ctx, doc.ExternalID, doc.PartitionKey, time.Now())
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+		err = b.deleteNodePoolDocs(ctx, doc)
+		if err != nil {
+			return err
+		}
+		err = b.dbClient.DeleteClusterDoc(ctx, doc.ExternalID, doc.PartitionKey)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+		return b.updateOperation(ctx, doc, arm.ProvisioningStateSucceeded, nil)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch cluster %s from clusters-service: %w", doc.InternalID, err)
+	}
+
+	state := frontend.ConvertCSClusterStateToProvisioningState(cluster.State())
+
+	var operationError *arm.CloudErrorBody
+	switch {
+	case doc.Request == database.OperationRequestDelete:
+		// The cluster is gone once Cluster Service returns 404, even
+		// if it was in an error state when the deletion started.
+		state = arm.ProvisioningStateDeleting
+	case state == arm.ProvisioningStateFailed:
+		operationError = &arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInternalServerError,
+			Message: clusterErrorMessage(cluster),
+		}
+	case state == "":
+		return fmt.Errorf("unrecognized state %q for cluster %s", cluster.State(), doc.InternalID)
+	case state == arm.ProvisioningStateSucceeded && doc.Request == database.OperationRequestCreate:
+		// Record billing before completing the operation so a
+		// failure here is retried on the next poll.
+		if err := b.createBillingDoc(ctx, doc, cluster); err != nil {
+			return err
+		}
+	}
+
+	// Record the state in the cluster document before completing the
+	// operation, since a terminal operation is never polled again.
+	if err := b.setClusterProvisioningState(ctx, doc, state); err != nil {
+		return err
+	}
+
+	return b.updateOperation(ctx, doc, state, operationError)
+}
+
+func (b *Backend) reconcileNodePoolOperation(ctx context.Context, doc *database.OperationDocument) error {
+	clusterResourceID := path.Dir(path.Dir(doc.ExternalID))
+	clusterDoc, err := b.dbClient.GetClusterDoc(ctx, clusterResourceID, doc.PartitionKey)
+	if errors.Is(err, database.ErrNotFound) {
+		// The cluster was deleted along with its node pools.
+		err = b.dbClient.DeleteNodePoolDoc(ctx, doc.ExternalID, doc.PartitionKey)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+		return b.endOrphanedNodePoolOperation(ctx, doc)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch parent cluster document %s: %w", clusterResourceID, err)
+	}
+
+	nodePool, err := b.csClient.GetCSNodePool(ctx, clusterDoc.ClusterID, doc.InternalID)
+	if ocm.IsNotFound(err) {
+		if doc.Request != database.OperationRequestDelete {
+			return b.updateOperation(ctx, doc, arm.ProvisioningStateFailed, &arm.CloudErrorBody{
+				Code:    arm.CloudErrorCodeInternalServerError,
+				Message: "The node pool no longer exists.",
+			})
+		}
+		err = b.dbClient.DeleteNodePoolDoc(ctx, doc.ExternalID, doc.PartitionKey)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+		return b.updateOperation(ctx, doc, arm.ProvisioningStateSucceeded, nil)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch node pool %s from clusters-service: %w", doc.InternalID, err)
+	}
+
+	state := frontend.ConvertCSNodePoolStatusToProvisioningState(nodePool)
+
+	var operationError *arm.CloudErrorBody
+	switch {
+	case doc.Request == database.OperationRequestDelete:
+		// The node pool is gone once Cluster Service returns 404.
+		state = arm.ProvisioningStateDeleting
+	case state == arm.ProvisioningStateFailed:
+		operationError = &arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInternalServerError,
+			Message: nodePool.Status().Message(),
+		}
+	case state == arm.ProvisioningStateSucceeded:
+	case doc.Request == database.OperationRequestUpdate:
+		state = arm.ProvisioningStateUpdating
+	case state != arm.ProvisioningStateAccepted:
+		state = arm.ProvisioningStateProvisioning
+	}
+
+	// Record the state in the node pool document before completing the
+	// operation, since a terminal operation is never polled again.
+	if err := b.setNodePoolProvisioningState(ctx, doc, state); err != nil {
+		return err
+	}
+
+	return b.updateOperation(ctx, doc, state, operationError)
+}
+
+// deleteNodePoolDocs removes the node pool documents of the deleted cluster
+// an operation refers to, ending any operations still active on them since
+// their node pools can no longer be polled.
+func (b *Backend) deleteNodePoolDocs(ctx context.Context, doc *database.OperationDocument) error {
+	nodePoolDocs, err := b.dbClient.ListNodePoolDocs(ctx, doc.ExternalID, doc.PartitionKey)
+	if err != nil {
+		return fmt.Errorf("failed to list node pool documents of %s: %w", doc.ExternalID, err)
+	}
+
+	for _, nodePoolDoc := range nodePoolDocs {
+		operationDocs, err := b.dbClient.ListOperationDocs(ctx, nodePoolDoc.Key, doc.PartitionKey)
+		if err != nil {
+			return fmt.Errorf("failed to list operations on %s: %w", nodePoolDoc.Key, err)
+		}
+		for _, operationDoc := range operationDocs {
+			if operationDoc.Status.IsTerminal() {
+				continue
+			}
+			if err := b.endOrphanedNodePoolOperation(ctx, operationDoc); err != nil {
+				return err
+			}
+		}
+
+		err = b.dbClient.DeleteNodePoolDoc(ctx, nodePoolDoc.Key, doc.PartitionKey)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// endOrphanedNodePoolOperation completes an operation on a node pool whose
+// cluster no longer exists. Deleting the node pool succeeds; anything else
+// fails.
+func (b *Backend) endOrphanedNodePoolOperation(ctx context.Context, doc *database.OperationDocument) error {
+	if doc.Request == database.OperationRequestDelete {
+		return b.updateOperation(ctx, doc, arm.ProvisioningStateSucceeded, nil)
+	}
+	return b.updateOperation(ctx, doc, arm.ProvisioningStateFailed, &arm.CloudErrorBody{
+		Code:    arm.CloudErrorCodeInternalServerError,
+		Message: "The cluster no longer exists.",
+	})
+}
+
+// reconcileSubscriptionOperation handles a subscription state change
+// recorded by the frontend. Clusters in a warned or suspended subscription
+// are currently left running; the change is only logged so that policy,
+// such as scaling node pools to zero, can be added here.
+func (b *Backend) reconcileSubscriptionOperation(ctx context.Context, doc *database.OperationDocument) error {
+	b.logger.Info(fmt.Sprintf("subscription %s state change: %s", doc.PartitionKey, doc.Request))
+	return b.updateOperation(ctx, doc, arm.ProvisioningStateSucceeded, nil)
+}
+
+// createBillingDoc starts the billing record for a newly created cluster
+// unless one already exists.
+func (b *Backend) createBillingDoc(ctx context.Context, doc *database.OperationDocument, cluster *cmv1.Cluster) error {
+	_, err := b.dbClient.GetBillingDoc(ctx, doc.ExternalID, doc.PartitionKey)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+
+	billingDoc := database.NewBillingDocument(
+		doc.PartitionKey,
+		cluster.Azure().TenantID(),
+		cluster.Region().ID(),
+		doc.ExternalID,
+		time.Now())
+	err = b.dbClient.CreateBillingDoc(ctx, billingDoc)
+	if err != nil {
+		return fmt.Errorf("failed to create billing document for %s: %w", doc.ExternalID, err)
+	}
+
+	b.logger.Info(fmt.Sprintf("billing started for %s", doc.ExternalID))
+	return nil
+}
+
+// setClusterProvisioningState writes the provisioning state to the document
+// of the cluster an operation refers to. If other writers keep updating the
+// document first, the operation stays active and the next poll tries again.
+func (b *Backend) setClusterProvisioningState(ctx context.Context, doc *database.OperationDocument, state arm.ProvisioningState) error {
+	return database.UpdateClusterDoc(ctx, b.dbClient, doc.ExternalID, doc.PartitionKey, func(clusterDoc *database.HCPOpenShiftClusterDocument) bool {
+		if clusterDoc.ProvisioningState == state {
+			return false
+		}
+		clusterDoc.ProvisioningState = state
+		return true
+	})
+}
+
+// setNodePoolProvisioningState is like setClusterProvisioningState for
+// node pools.
+func (b *Backend) setNodePoolProvisioningState(ctx context.Context, doc *database.OperationDocument, state arm.ProvisioningState) error {
+	return database.UpdateNodePoolDoc(ctx, b.dbClient, doc.ExternalID, doc.PartitionKey, func(nodePoolDoc *database.NodePoolDocument) bool {
+		if nodePoolDoc.ProvisioningState == state {
+			return false
+		}
+		nodePoolDoc.ProvisioningState = state
+		return true
+	})
+}
+
+// updateOperation writes the operation status only if it changed.
+func (b *Backend) updateOperation(ctx context.Context, doc *database.OperationDocument, status arm.ProvisioningState, operationError *arm.CloudErrorBody) error {
+	if doc.Status == status {
+		return nil
+	}
+
+	_, err := b.dbClient.UpdateOperationStatus(ctx, doc.ID, doc.PartitionKey, status, operationError)
+	if isConflictError(err) {
+		// Another writer updated the operation first. Unless that
+		// completed it, the next poll reconciles it again.
+		b.logger.Info(fmt.Sprintf("operation %s on %s changed concurrently", doc.ID, doc.ExternalID))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	b.logger.Info(fmt.Sprintf("operation %s on %s is now %s", doc.ID, doc.ExternalID, status))
+	return nil
+}
+
+// clusterErrorMessage returns the reason Cluster Service gives for a
+// cluster in an error state.
+func clusterErrorMessage(cluster *cmv1.Cluster) string {
+	if description := cluster.Status().Description(); description != "" {
+		return description
+	}
+	return "The cluster failed to provision."
+}
+
+// isConflictError returns true if another writer updated the document
+// first.
+func isConflictError(err error) bool {
+	var conflictError *database.ConflictError
+	return errors.As(err, &conflictError)
+}
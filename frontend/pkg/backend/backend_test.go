@@ -0,0 +1,649 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestReconcileClusterOperation(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	tests := []struct {
+		name                      string
+		request                   database.OperationRequest
+		clusterState              string
+		expectedStatus            arm.ProvisioningState
+		expectedProvisioningState arm.ProvisioningState
+		expectDocDeleted          bool
+		expectBilling             bool
+	}{
+		{
+			name:                      "Create still installing",
+			request:                   database.OperationRequestCreate,
+			clusterState:              "installing",
+			expectedStatus:            arm.ProvisioningStateProvisioning,
+			expectedProvisioningState: arm.ProvisioningStateProvisioning,
+		},
+		{
+			name:                      "Create ready",
+			request:                   database.OperationRequestCreate,
+			clusterState:              "ready",
+			expectedStatus:            arm.ProvisioningStateSucceeded,
+			expectedProvisioningState: arm.ProvisioningStateSucceeded,
+			expectBilling:             true,
+		},
+		{
+			name:                      "Create failed",
+			request:                   database.OperationRequestCreate,
+			clusterState:              "error",
+			expectedStatus:            arm.ProvisioningStateFailed,
+			expectedProvisioningState: arm.ProvisioningStateFailed,
+		},
+		{
+			name:                      "Delete in progress",
+			request:                   database.OperationRequestDelete,
+			clusterState:              "uninstalling",
+			expectedStatus:            arm.ProvisioningStateDeleting,
+			expectedProvisioningState: arm.ProvisioningStateDeleting,
+			expectBilling:             true,
+		},
+		{
+			name:                      "Delete of a failed cluster in progress",
+			request:                   database.OperationRequestDelete,
+			clusterState:              "error",
+			expectedStatus:            arm.ProvisioningStateDeleting,
+			expectedProvisioningState: arm.ProvisioningStateDeleting,
+			expectBilling:             true,
+		},
+		{
+			name:             "Delete complete",
+			request:          database.OperationRequestDelete,
+			expectedStatus:   arm.ProvisioningStateSucceeded,
+			expectDocDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if test.clusterState == "" || r.URL.Path != "/api/clusters_mgmt/v1/clusters/cluster-id" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"kind": "Error", "id": "404", "reason": "Cluster not found"}`))
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"kind": "Cluster", "id": "cluster-id", "state": %q, "region": {"id": "eastus"}, "azure": {"tenant_id": "tenant-id"}}`, test.clusterState)
+			}))
+			defer cs.Close()
+
+			conn, err := sdk.NewUnauthenticatedConnectionBuilder().URL(cs.URL).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			ctx := context.TODO()
+			dbClient := database.NewCache()
+			b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, ocm.NewClusterServiceClient(conn), "holder")
+
+			resourceID := strings.ToLower(clusterPath)
+			err = dbClient.SetClusterDoc(ctx, &database.HCPOpenShiftClusterDocument{
+				Key:               resourceID,
+				PartitionKey:      subscriptionID,
+				ClusterID:         "cluster-id",
+				ProvisioningState: arm.ProvisioningStateAccepted,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.request == database.OperationRequestDelete {
+				billingDoc := database.NewBillingDocument(subscriptionID, "tenant-id", "eastus", resourceID, time.Now())
+				err = dbClient.CreateBillingDoc(ctx, billingDoc)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			operationDoc := database.NewOperationDocument(test.request, subscriptionID, resourceID, "cluster-id")
+			err = dbClient.CreateOperationDoc(ctx, operationDoc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b.poll(ctx)
+
+			billingDoc, err := dbClient.GetBillingDoc(ctx, resourceID, subscriptionID)
+			if test.expectBilling {
+				if err != nil {
+					t.Fatalf("expected a billing document, got %v", err)
+				}
+				if billingDoc.TenantID != "tenant-id" || billingDoc.Location != "eastus" || billingDoc.DeletionTime != nil {
+					t.Errorf("expected active billing document for tenant-id in eastus, got %+v", billingDoc)
+				}
+			} else if !errors.Is(err, database.ErrNotFound) {
+				// A deleted cluster's billing document has its
+				// deletion time set and is no longer returned.
+				t.Errorf("expected no active billing document, got %v", err)
+			}
+
+			operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operationDoc.Status != test.expectedStatus {
+				t.Errorf("expected operation status %q, got %q", test.expectedStatus, operationDoc.Status)
+			}
+			if test.expectedStatus == arm.ProvisioningStateFailed && operationDoc.Error == nil {
+				t.Error("expected an operation error")
+			}
+
+			clusterDoc, err := dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
+			if test.expectDocDeleted {
+				if !errors.Is(err, database.ErrNotFound) {
+					t.Errorf("expected cluster document to be deleted, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clusterDoc.ProvisioningState != test.expectedProvisioningState {
+				t.Errorf("expected provisioning state %q, got %q", test.expectedProvisioningState, clusterDoc.ProvisioningState)
+			}
+		})
+	}
+}
+
+func TestReconcileNodePoolOperation(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterResourceID = "/subscriptions/" + subscriptionID + "/resourcegroups/myrg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/mycluster"
+	const resourceID = clusterResourceID + "/nodepools/mynodepool"
+
+	tests := []struct {
+		name           string
+		request        database.OperationRequest
+		status         *cmv1.NodePoolStatusBuilder
+		expectedStatus arm.ProvisioningState
+		expectedError  string
+	}{
+		{
+			name:           "Create scaling up",
+			request:        database.OperationRequestCreate,
+			status:         cmv1.NewNodePoolStatus().CurrentReplicas(1),
+			expectedStatus: arm.ProvisioningStateProvisioning,
+		},
+		{
+			name:           "Create ready",
+			request:        database.OperationRequestCreate,
+			status:         cmv1.NewNodePoolStatus().CurrentReplicas(3),
+			expectedStatus: arm.ProvisioningStateSucceeded,
+		},
+		{
+			name:           "Create failed",
+			request:        database.OperationRequestCreate,
+			status:         cmv1.NewNodePoolStatus().CurrentReplicas(1).Message("insufficient quota"),
+			expectedStatus: arm.ProvisioningStateFailed,
+			expectedError:  "insufficient quota",
+		},
+		{
+			name:           "Update failed",
+			request:        database.OperationRequestUpdate,
+			status:         cmv1.NewNodePoolStatus().CurrentReplicas(1).Message("insufficient quota"),
+			expectedStatus: arm.ProvisioningStateFailed,
+			expectedError:  "insufficient quota",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			dbClient := database.NewCache()
+			csClient := ocm.NewMockClusterServiceClient()
+			b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, csClient, "holder")
+
+			cluster, err := cmv1.NewCluster().Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cluster, err = csClient.PostCSCluster(ctx, cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodePool, err := cmv1.NewNodePool().ID("node-pool-id").Replicas(3).Status(test.status).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = csClient.PostCSNodePool(ctx, cluster.ID(), nodePool)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = dbClient.SetClusterDoc(ctx, &database.HCPOpenShiftClusterDocument{
+				Key:          clusterResourceID,
+				PartitionKey: subscriptionID,
+				ClusterID:    cluster.ID(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dbClient.SetNodePoolDoc(ctx, &database.NodePoolDocument{
+				Key:               resourceID,
+				PartitionKey:      subscriptionID,
+				NodePoolID:        "node-pool-id",
+				ProvisioningState: arm.ProvisioningStateAccepted,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			operationDoc := database.NewOperationDocument(test.request, subscriptionID, resourceID, "node-pool-id")
+			err = dbClient.CreateOperationDoc(ctx, operationDoc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b.poll(ctx)
+
+			operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operationDoc.Status != test.expectedStatus {
+				t.Errorf("expected operation status %q, got %q", test.expectedStatus, operationDoc.Status)
+			}
+			if test.expectedError != "" && (operationDoc.Error == nil || operationDoc.Error.Message != test.expectedError) {
+				t.Errorf("expected operation error %q, got %+v", test.expectedError, operationDoc.Error)
+			}
+
+			nodePoolDoc, err := dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nodePoolDoc.ProvisioningState != test.expectedStatus {
+				t.Errorf("expected provisioning state %q, got %q", test.expectedStatus, nodePoolDoc.ProvisioningState)
+			}
+		})
+	}
+}
+
+func TestReconcileClusterDeletionEndsNodePools(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const resourceID = "/subscriptions/" + subscriptionID + "/resourcegroups/myrg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/mycluster"
+
+	ctx := context.TODO()
+	dbClient := database.NewCache()
+	b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, ocm.NewMockClusterServiceClient(), "holder")
+
+	err := dbClient.SetClusterDoc(ctx, &database.HCPOpenShiftClusterDocument{
+		Key:               resourceID,
+		PartitionKey:      subscriptionID,
+		ClusterID:         "cluster-id",
+		ProvisioningState: arm.ProvisioningStateDeleting,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodePoolRequests := map[string]database.OperationRequest{
+		"np1": database.OperationRequestCreate,
+		"np2": database.OperationRequestDelete,
+	}
+	nodePoolOperations := map[string]*database.OperationDocument{}
+	for name, request := range nodePoolRequests {
+		nodePoolID := resourceID + "/nodepools/" + name
+		err = dbClient.SetNodePoolDoc(ctx, &database.NodePoolDocument{
+			Key:          nodePoolID,
+			PartitionKey: subscriptionID,
+			NodePoolID:   name + "-id",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		operationDoc := database.NewOperationDocument(request, subscriptionID, nodePoolID, name+"-id")
+		err = dbClient.CreateOperationDoc(ctx, operationDoc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodePoolOperations[name] = operationDoc
+	}
+
+	operationDoc := database.NewOperationDocument(database.OperationRequestDelete, subscriptionID, resourceID, "cluster-id")
+	err = dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconcile only the cluster operation so the node pool operations
+	// are ended by the cluster deletion rather than polled themselves.
+	err = b.reconcileOperation(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodePoolDocs, err := dbClient.ListNodePoolDocs(ctx, resourceID, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodePoolDocs) != 0 {
+		t.Errorf("expected node pool documents to be deleted, got %d", len(nodePoolDocs))
+	}
+
+	for name, expectedStatus := range map[string]arm.ProvisioningState{
+		"np1": arm.ProvisioningStateFailed,
+		"np2": arm.ProvisioningStateSucceeded,
+	} {
+		nodePoolOperation, err := dbClient.GetOperationDoc(ctx, nodePoolOperations[name].ID, subscriptionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodePoolOperation.Status != expectedStatus {
+			t.Errorf("expected %s operation status %q, got %q", name, expectedStatus, nodePoolOperation.Status)
+		}
+		if expectedStatus == arm.ProvisioningStateFailed && nodePoolOperation.Error == nil {
+			t.Errorf("expected an operation error for %s", name)
+		}
+	}
+}
+
+func TestReconcileNodePoolOperationWithoutCluster(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const resourceID = "/subscriptions/" + subscriptionID + "/resourcegroups/myrg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/mycluster/nodepools/mynodepool"
+
+	tests := []struct {
+		name           string
+		request        database.OperationRequest
+		expectedStatus arm.ProvisioningState
+	}{
+		{
+			name:           "Create fails",
+			request:        database.OperationRequestCreate,
+			expectedStatus: arm.ProvisioningStateFailed,
+		},
+		{
+			name:           "Delete succeeds",
+			request:        database.OperationRequestDelete,
+			expectedStatus: arm.ProvisioningStateSucceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			dbClient := database.NewCache()
+
+			csClient := ocm.NewMockClusterServiceClient()
+			csClient.Err = errors.New("unexpected request to Cluster Service")
+			b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, csClient, "holder")
+
+			err := dbClient.SetNodePoolDoc(ctx, &database.NodePoolDocument{
+				Key:          resourceID,
+				PartitionKey: subscriptionID,
+				NodePoolID:   "node-pool-id",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			operationDoc := database.NewOperationDocument(test.request, subscriptionID, resourceID, "node-pool-id")
+			err = dbClient.CreateOperationDoc(ctx, operationDoc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b.poll(ctx)
+
+			operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operationDoc.Status != test.expectedStatus {
+				t.Errorf("expected operation status %q, got %q", test.expectedStatus, operationDoc.Status)
+			}
+			if test.expectedStatus == arm.ProvisioningStateFailed && operationDoc.Error == nil {
+				t.Error("expected an operation error")
+			}
+
+			_, err = dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+			if !errors.Is(err, database.ErrNotFound) {
+				t.Errorf("expected node pool document to be deleted, got %v", err)
+			}
+		})
+	}
+}
+
+// conflictingDBClient simulates another writer updating a cluster document
+// just before each of the next conflicts calls to SetClusterDoc.
+type conflictingDBClient struct {
+	database.DBClient
+	conflicts int
+}
+
+func (c *conflictingDBClient) SetClusterDoc(ctx context.Context, doc *database.HCPOpenShiftClusterDocument) error {
+	if c.conflicts > 0 {
+		c.conflicts--
+		other, err := c.DBClient.GetClusterDoc(ctx, doc.Key, doc.PartitionKey)
+		if err != nil {
+			return err
+		}
+		other.Tags = map[string]string{"writer": "other"}
+		if err = c.DBClient.SetClusterDoc(ctx, other); err != nil {
+			return err
+		}
+	}
+	return c.DBClient.SetClusterDoc(ctx, doc)
+}
+
+func TestReconcileClusterOperationConflict(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const resourceID = "/subscriptions/" + subscriptionID + "/resourcegroups/myrg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/mycluster"
+
+	tests := []struct {
+		name                      string
+		conflicts                 int
+		expectedStatus            arm.ProvisioningState
+		expectedProvisioningState arm.ProvisioningState
+	}{
+		{
+			name:                      "Conflict is retried",
+			conflicts:                 1,
+			expectedStatus:            arm.ProvisioningStateFailed,
+			expectedProvisioningState: arm.ProvisioningStateFailed,
+		},
+		{
+			name:                      "Persistent conflict leaves the operation active",
+			conflicts:                 database.MaxConflictRetries,
+			expectedStatus:            arm.ProvisioningStateAccepted,
+			expectedProvisioningState: arm.ProvisioningStateAccepted,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"kind": "Cluster", "id": "cluster-id", "state": "error", "region": {"id": "eastus"}}`))
+			}))
+			defer cs.Close()
+
+			conn, err := sdk.NewUnauthenticatedConnectionBuilder().URL(cs.URL).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			ctx := context.TODO()
+			dbClient := &conflictingDBClient{DBClient: database.NewCache()}
+			b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, ocm.NewClusterServiceClient(conn), "holder")
+
+			err = dbClient.SetClusterDoc(ctx, &database.HCPOpenShiftClusterDocument{
+				Key:               resourceID,
+				PartitionKey:      subscriptionID,
+				ClusterID:         "cluster-id",
+				ProvisioningState: arm.ProvisioningStateAccepted,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			operationDoc := database.NewOperationDocument(database.OperationRequestCreate, subscriptionID, resourceID, "cluster-id")
+			err = dbClient.CreateOperationDoc(ctx, operationDoc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dbClient.conflicts = test.conflicts
+			b.poll(ctx)
+
+			operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operationDoc.Status != test.expectedStatus {
+				t.Errorf("expected operation status %q, got %q", test.expectedStatus, operationDoc.Status)
+			}
+
+			clusterDoc, err := dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clusterDoc.ProvisioningState != test.expectedProvisioningState {
+				t.Errorf("expected provisioning state %q, got %q", test.expectedProvisioningState, clusterDoc.ProvisioningState)
+			}
+			if clusterDoc.Tags["writer"] != "other" {
+				t.Errorf("expected the other writer's change to be kept, got tags %v", clusterDoc.Tags)
+			}
+
+			// An active operation is reconciled again on the next poll.
+			b.poll(ctx)
+
+			operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clusterDoc, err = dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operationDoc.Status != arm.ProvisioningStateFailed || clusterDoc.ProvisioningState != arm.ProvisioningStateFailed {
+				t.Errorf("expected operation status and provisioning state %q, got %q and %q",
+					arm.ProvisioningStateFailed, operationDoc.Status, clusterDoc.ProvisioningState)
+			}
+		})
+	}
+}
+
+func TestPollWithoutLease(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	ctx := context.TODO()
+	dbClient := database.NewCache()
+
+	acquired, err := dbClient.AcquireLease(ctx, LeaseName, "other", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Fatal("expected lease to be acquired")
+	}
+
+	operationDoc := database.NewOperationDocument(database.OperationRequestCreate, subscriptionID, "/resource/id", "cluster-id")
+	err = dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csClient := ocm.NewMockClusterServiceClient()
+	csClient.Err = errors.New("unexpected request to Cluster Service")
+	b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, csClient, "holder")
+	b.poll(ctx)
+
+	operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operationDoc.Status != arm.ProvisioningStateAccepted {
+		t.Errorf("expected operation status %q, got %q", arm.ProvisioningStateAccepted, operationDoc.Status)
+	}
+}
+
+// slowDBClient simulates a poll that outlasts the lease by blocking in
+// ListActiveOperationDocs until the poll's context is done.
+type slowDBClient struct {
+	database.DBClient
+}
+
+func (c *slowDBClient) ListActiveOperationDocs(ctx context.Context) ([]*database.OperationDocument, error) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+	return c.DBClient.ListActiveOperationDocs(context.Background())
+}
+
+func TestPollStopsBeforeLeaseExpires(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	ctx := context.TODO()
+	dbClient := &slowDBClient{DBClient: database.NewCache()}
+
+	operationDoc := database.NewOperationDocument(database.OperationRequestSuspend, subscriptionID, "/subscriptions/"+subscriptionID, "")
+	err := dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, ocm.NewMockClusterServiceClient(), "holder")
+	b.leaseDuration = 100 * time.Millisecond
+	b.poll(ctx)
+
+	operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operationDoc.Status != arm.ProvisioningStateAccepted {
+		t.Errorf("expected operation status %q, got %q", arm.ProvisioningStateAccepted, operationDoc.Status)
+	}
+}
+
+func TestReconcileSubscriptionOperation(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	ctx := context.TODO()
+	dbClient := database.NewCache()
+
+	operationDoc := database.NewOperationDocument(database.OperationRequestSuspend, subscriptionID, "/subscriptions/"+subscriptionID, "")
+	err := dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csClient := ocm.NewMockClusterServiceClient()
+	csClient.Err = errors.New("unexpected request to Cluster Service")
+	b := NewBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), dbClient, csClient, "holder")
+	b.poll(ctx)
+
+	operationDoc, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operationDoc.Status != arm.ProvisioningStateSucceeded {
+		t.Errorf("expected operation status %q, got %q", arm.ProvisioningStateSucceeded, operationDoc.Status)
+	}
+}
@@ -0,0 +1,639 @@
+// Package csfake provides an in-process fake of the Cluster Service
+// clusters_mgmt v1 API for hermetic tests.
+package csfake
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+// APIPrefix is the path prefix of every clusters_mgmt v1 endpoint.
+const APIPrefix = "/api/clusters_mgmt/v1"
+
+// Default credentials returned for clusters without explicit ones.
+const (
+	DefaultKubeconfig        = "apiVersion: v1\nkind: Config\n"
+	DefaultKubeadminUsername = "kubeadmin"
+	DefaultKubeadminPassword = "password"
+)
+
+// object is a Cluster Service resource in its JSON wire format. Keeping
+// the wire format lets PATCH requests merge like the real service and
+// lets search expressions address arbitrary attributes.
+type object map[string]any
+
+type credentials struct {
+	kubeconfig string
+	username   string
+	password   string
+}
+
+type injectedError struct {
+	status int
+	reason string
+}
+
+// Server is an httptest.Server that serves clusters, node pools, versions
+// and cluster credentials from an in-memory store. It is safe for
+// concurrent use.
+//
+// New clusters start in the "pending" state and node pools report no
+// status. Tests drive them forward with SetClusterState and
+// SetNodePoolCurrentReplicas. Deleting a cluster moves it to the
+// "uninstalling" state; RemoveCluster completes the deletion.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	clusters    map[string]object
+	nodePools   map[string]map[string]object
+	versions    map[string]object
+	credentials map[string]credentials
+	errors      map[string]injectedError
+	hooks       map[string]func()
+}
+
+// NewServer starts and returns a new Server. The caller should call Close
+// when finished.
+func NewServer() *Server {
+	s := &Server{
+		clusters:    make(map[string]object),
+		nodePools:   make(map[string]map[string]object),
+		versions:    make(map[string]object),
+		credentials: make(map[string]credentials),
+		errors:      make(map[string]injectedError),
+		hooks:       make(map[string]func()),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+APIPrefix+"/clusters", s.listClusters)
+	mux.HandleFunc("POST "+APIPrefix+"/clusters", s.addCluster)
+	mux.HandleFunc("GET "+APIPrefix+"/clusters/{cluster}", s.getCluster)
+	mux.HandleFunc("PATCH "+APIPrefix+"/clusters/{cluster}", s.updateCluster)
+	mux.HandleFunc("DELETE "+APIPrefix+"/clusters/{cluster}", s.deleteCluster)
+	mux.HandleFunc("GET "+APIPrefix+"/clusters/{cluster}/credentials", s.getCredentials)
+	mux.HandleFunc("GET "+APIPrefix+"/clusters/{cluster}/node_pools", s.listNodePools)
+	mux.HandleFunc("POST "+APIPrefix+"/clusters/{cluster}/node_pools", s.addNodePool)
+	mux.HandleFunc("GET "+APIPrefix+"/clusters/{cluster}/node_pools/{nodepool}", s.getNodePool)
+	mux.HandleFunc("PATCH "+APIPrefix+"/clusters/{cluster}/node_pools/{nodepool}", s.updateNodePool)
+	mux.HandleFunc("DELETE "+APIPrefix+"/clusters/{cluster}/node_pools/{nodepool}", s.deleteNodePool)
+	mux.HandleFunc("GET "+APIPrefix+"/versions", s.listVersions)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Path '%s' not found", r.URL.Path))
+	})
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// Connect returns an unauthenticated SDK connection to the server.
+func (s *Server) Connect() (*sdk.Connection, error) {
+	return sdk.NewUnauthenticatedConnectionBuilder().URL(s.URL).Build()
+}
+
+// InjectError makes every request with the given method and path fail
+// with the given HTTP status until ClearErrors is called. The path is
+// relative to APIPrefix, for example "/clusters/abc".
+func (s *Server) InjectError(method, path string, status int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[method+" "+APIPrefix+path] = injectedError{status: status, reason: reason}
+}
+
+// BeforeRequest makes every request with the given method and path call
+// hook before it is served, for example to simulate a concurrent client.
+// The path is relative to APIPrefix, as for InjectError.
+func (s *Server) BeforeRequest(method, path string, hook func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[method+" "+APIPrefix+path] = hook
+}
+
+// ClearErrors removes all errors added with InjectError.
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.errors)
+}
+
+// AddCluster stores a cluster as though it had been created through the
+// API. A cluster without an ID is assigned one, which is returned.
+func (s *Server) AddCluster(cluster *cmv1.Cluster) (string, error) {
+	obj, err := marshal(cluster, cmv1.MarshalCluster)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storeCluster(obj), nil
+}
+
+// AddNodePool stores a node pool under an existing cluster as though it
+// had been created through the API.
+func (s *Server) AddNodePool(clusterID string, nodePool *cmv1.NodePool) error {
+	obj, err := marshal(nodePool, cmv1.MarshalNodePool)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clusters[clusterID]; !ok {
+		return fmt.Errorf("cluster %s not found", clusterID)
+	}
+	s.storeNodePool(clusterID, obj)
+	return nil
+}
+
+// AddVersion makes a version available for listing.
+func (s *Server) AddVersion(version *cmv1.Version) error {
+	obj, err := marshal(version, cmv1.MarshalVersion)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := obj["id"].(string)
+	obj["kind"] = "Version"
+	obj["href"] = APIPrefix + "/versions/" + id
+	s.versions[id] = obj
+	return nil
+}
+
+// Cluster returns the stored cluster with the given ID.
+func (s *Server) Cluster(clusterID string) (*cmv1.Cluster, error) {
+	s.mu.Lock()
+	obj, ok := s.clusters[clusterID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	return unmarshal(obj, cmv1.UnmarshalCluster)
+}
+
+// NodePool returns the stored node pool with the given IDs.
+func (s *Server) NodePool(clusterID, nodePoolID string) (*cmv1.NodePool, error) {
+	s.mu.Lock()
+	obj, ok := s.nodePools[clusterID][nodePoolID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("node pool %s/%s not found", clusterID, nodePoolID)
+	}
+	return unmarshal(obj, cmv1.UnmarshalNodePool)
+}
+
+// SetClusterState changes the state of a stored cluster.
+func (s *Server) SetClusterState(clusterID string, state cmv1.ClusterState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.clusters[clusterID]
+	if !ok {
+		return fmt.Errorf("cluster %s not found", clusterID)
+	}
+	obj["state"] = string(state)
+	return nil
+}
+
+// SetNodePoolCurrentReplicas changes the number of ready replicas a
+// stored node pool reports.
+func (s *Server) SetNodePoolCurrentReplicas(clusterID, nodePoolID string, replicas int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.nodePools[clusterID][nodePoolID]
+	if !ok {
+		return fmt.Errorf("node pool %s/%s not found", clusterID, nodePoolID)
+	}
+	obj["status"] = map[string]any{
+		"kind":             "NodePoolStatus",
+		"current_replicas": replicas,
+	}
+	return nil
+}
+
+// SetClusterCredentials changes the credentials returned for a cluster.
+func (s *Server) SetClusterCredentials(clusterID, kubeconfig, username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[clusterID] = credentials{
+		kubeconfig: kubeconfig,
+		username:   username,
+		password:   password,
+	}
+}
+
+// RemoveCluster completes the deletion of a cluster and its node pools.
+func (s *Server) RemoveCluster(clusterID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clusters, clusterID)
+	delete(s.nodePools, clusterID)
+	delete(s.credentials, clusterID)
+}
+
+// intercept runs the hooks added with BeforeRequest and serves the errors
+// added with InjectError.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		hook := s.hooks[r.Method+" "+r.URL.Path]
+		injected, ok := s.errors[r.Method+" "+r.URL.Path]
+		s.mu.Unlock()
+		if hook != nil {
+			hook()
+		}
+		if ok {
+			writeError(w, injected.status, injected.reason)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// storeCluster must be called with s.mu held.
+func (s *Server) storeCluster(obj object) string {
+	id, _ := obj["id"].(string)
+	if id == "" {
+		id = strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+	obj["kind"] = "Cluster"
+	obj["id"] = id
+	obj["href"] = APIPrefix + "/clusters/" + id
+	if _, ok := obj["state"]; !ok {
+		obj["state"] = string(cmv1.ClusterStatePending)
+	}
+	s.clusters[id] = obj
+	return id
+}
+
+// storeNodePool must be called with s.mu held.
+func (s *Server) storeNodePool(clusterID string, obj object) {
+	id, _ := obj["id"].(string)
+	obj["kind"] = "NodePool"
+	obj["href"] = APIPrefix + "/clusters/" + clusterID + "/node_pools/" + id
+	if s.nodePools[clusterID] == nil {
+		s.nodePools[clusterID] = make(map[string]object)
+	}
+	s.nodePools[clusterID][id] = obj
+}
+
+func (s *Server) listClusters(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeList(w, r, "ClusterList", s.clusters)
+}
+
+func (s *Server) addCluster(w http.ResponseWriter, r *http.Request) {
+	var obj object
+	if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	delete(obj, "id")
+	delete(obj, "state")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.storeCluster(obj)
+	writeJSON(w, http.StatusCreated, s.clusters[id])
+}
+
+func (s *Server) getCluster(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.clusters[r.PathValue("cluster")]
+	if !ok {
+		writeNotFound(w, "Cluster", r.PathValue("cluster"))
+		return
+	}
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func (s *Server) updateCluster(w http.ResponseWriter, r *http.Request) {
+	var patch object
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.clusters[r.PathValue("cluster")]
+	if !ok {
+		writeNotFound(w, "Cluster", r.PathValue("cluster"))
+		return
+	}
+	merge(obj, patch, "id", "kind", "href", "state")
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func (s *Server) deleteCluster(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.clusters[r.PathValue("cluster")]
+	if !ok {
+		writeNotFound(w, "Cluster", r.PathValue("cluster"))
+		return
+	}
+	obj["state"] = string(cmv1.ClusterStateUninstalling)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getCredentials(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clusterID := r.PathValue("cluster")
+	if _, ok := s.clusters[clusterID]; !ok {
+		writeNotFound(w, "Cluster", clusterID)
+		return
+	}
+	creds, ok := s.credentials[clusterID]
+	if !ok {
+		creds = credentials{
+			kubeconfig: DefaultKubeconfig,
+			username:   DefaultKubeadminUsername,
+			password:   DefaultKubeadminPassword,
+		}
+	}
+	writeJSON(w, http.StatusOK, object{
+		"kind":       "ClusterCredentials",
+		"id":         clusterID,
+		"href":       APIPrefix + "/clusters/" + clusterID + "/credentials",
+		"kubeconfig": creds.kubeconfig,
+		"admin": map[string]any{
+			"user":     creds.username,
+			"password": creds.password,
+		},
+	})
+}
+
+func (s *Server) listNodePools(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clusterID := r.PathValue("cluster")
+	if _, ok := s.clusters[clusterID]; !ok {
+		writeNotFound(w, "Cluster", clusterID)
+		return
+	}
+	writeList(w, r, "NodePoolList", s.nodePools[clusterID])
+}
+
+func (s *Server) addNodePool(w http.ResponseWriter, r *http.Request) {
+	var obj object
+	if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	delete(obj, "status")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clusterID := r.PathValue("cluster")
+	if _, ok := s.clusters[clusterID]; !ok {
+		writeNotFound(w, "Cluster", clusterID)
+		return
+	}
+	id, _ := obj["id"].(string)
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "Node pool ID is required")
+		return
+	}
+	if _, ok := s.nodePools[clusterID][id]; ok {
+		writeError(w, http.StatusConflict, fmt.Sprintf("Node pool '%s' already exists", id))
+		return
+	}
+	s.storeNodePool(clusterID, obj)
+	writeJSON(w, http.StatusCreated, obj)
+}
+
+func (s *Server) getNodePool(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.nodePools[r.PathValue("cluster")][r.PathValue("nodepool")]
+	if !ok {
+		writeNotFound(w, "Node pool", r.PathValue("nodepool"))
+		return
+	}
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func (s *Server) updateNodePool(w http.ResponseWriter, r *http.Request) {
+	var patch object
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.nodePools[r.PathValue("cluster")][r.PathValue("nodepool")]
+	if !ok {
+		writeNotFound(w, "Node pool", r.PathValue("nodepool"))
+		return
+	}
+	merge(obj, patch, "id", "kind", "href", "status")
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func (s *Server) deleteNodePool(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clusterID, nodePoolID := r.PathValue("cluster"), r.PathValue("nodepool")
+	if _, ok := s.nodePools[clusterID][nodePoolID]; !ok {
+		writeNotFound(w, "Node pool", nodePoolID)
+		return
+	}
+	delete(s.nodePools[clusterID], nodePoolID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listVersions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeList(w, r, "VersionList", s.versions)
+}
+
+// writeList writes the page of items selected by the "search", "page" and
+// "size" query parameters, ordered by ID.
+func writeList(w http.ResponseWriter, r *http.Request, kind string, items map[string]object) {
+	query := r.URL.Query()
+
+	match, err := parseSearch(query.Get("search"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, size := 1, 100
+	if value := query.Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid page '%s'", value))
+			return
+		}
+	}
+	if value := query.Get("size"); value != "" {
+		if size, err = strconv.Atoi(value); err != nil || size < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid size '%s'", value))
+			return
+		}
+	}
+
+	ids := make([]string, 0, len(items))
+	for id, obj := range items {
+		if match(obj) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	start := min((page-1)*size, len(ids))
+	end := min(start+size, len(ids))
+	pageItems := make([]object, 0, end-start)
+	for _, id := range ids[start:end] {
+		pageItems = append(pageItems, items[id])
+	}
+
+	writeJSON(w, http.StatusOK, object{
+		"kind":  kind,
+		"page":  page,
+		"size":  len(pageItems),
+		"total": len(ids),
+		"items": pageItems,
+	})
+}
+
+var (
+	searchAnd      = regexp.MustCompile(`(?i)\s+and\s+`)
+	searchClause   = regexp.MustCompile(`^\s*([a-z_.]+)\s*=\s*'([^']*)'\s*$`)
+	searchInClause = regexp.MustCompile(`(?i)^\s*([a-z_.]+)\s+in\s+\(((?:\s*'(?:[^']|'')*'\s*,?)*)\)\s*$`)
+	searchLiteral  = regexp.MustCompile(`'((?:[^']|'')*)'`)
+)
+
+// parseSearch supports the subset of the Cluster Service search language
+// used by the frontend: equality and IN clauses joined by AND.
+func parseSearch(search string) (func(object) bool, error) {
+	type clause struct {
+		path   []string
+		values []string
+	}
+
+	var clauses []clause
+	if strings.TrimSpace(search) != "" {
+		for _, part := range searchAnd.Split(search, -1) {
+			if m := searchClause.FindStringSubmatch(part); m != nil {
+				clauses = append(clauses, clause{path: strings.Split(m[1], "."), values: []string{m[2]}})
+				continue
+			}
+			if m := searchInClause.FindStringSubmatch(part); m != nil {
+				var values []string
+				for _, literal := range searchLiteral.FindAllStringSubmatch(m[2], -1) {
+					values = append(values, strings.ReplaceAll(literal[1], "''", "'"))
+				}
+				clauses = append(clauses, clause{path: strings.Split(m[1], "."), values: values})
+				continue
+			}
+			return nil, fmt.Errorf("unsupported search expression '%s'", part)
+		}
+	}
+
+	return func(obj object) bool {
+		for _, c := range clauses {
+			var value any = map[string]any(obj)
+			for _, key := range c.path {
+				m, ok := value.(map[string]any)
+				if !ok {
+					return false
+				}
+				value = m[key]
+			}
+			if value == nil || !slices.Contains(c.values, fmt.Sprint(value)) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// merge applies a JSON merge patch to obj, leaving the read-only keys
+// untouched.
+func merge(obj, patch object, readOnly ...string) {
+	for _, key := range readOnly {
+		delete(patch, key)
+	}
+	mergeMaps(obj, patch)
+}
+
+func mergeMaps(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		switch {
+		case value == nil:
+			delete(dst, key)
+		case srcIsMap && dstIsMap:
+			mergeMaps(dstMap, srcMap)
+		default:
+			dst[key] = value
+		}
+	}
+}
+
+func marshal[T any](v T, marshalFunc func(T, io.Writer) error) (object, error) {
+	var buf bytes.Buffer
+	if err := marshalFunc(v, &buf); err != nil {
+		return nil, err
+	}
+	var obj object
+	if err := json.Unmarshal(buf.Bytes(), &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func unmarshal[T any](obj object, unmarshalFunc func(any) (T, error)) (T, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return unmarshalFunc(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeNotFound(w http.ResponseWriter, kind, id string) {
+	writeError(w, http.StatusNotFound, fmt.Sprintf("%s '%s' not found", kind, id))
+}
+
+// writeError writes an error in the format of the real service.
+func writeError(w http.ResponseWriter, status int, reason string) {
+	writeJSON(w, status, object{
+		"kind":   "Error",
+		"id":     strconv.Itoa(status),
+		"href":   APIPrefix + "/errors/" + strconv.Itoa(status),
+		"code":   fmt.Sprintf("CLUSTERS-MGMT-%d", status),
+		"reason": reason,
+	})
+}
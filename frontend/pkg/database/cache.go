@@ -2,26 +2,162 @@ package database
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
 
 var _ DBClient = &Cache{}
 
 // Cache is a simple DBClient that allows us to perform simple tests without needing a real CosmosDB. For production,
-// use CosmosDBClient instead. Call NewCache() to initialize a Cache correctly.
+// use CosmosDBClient instead. Call NewCache() or NewPersistentCache() to initialize a Cache correctly.
+//
+// Cache is safe for concurrent use and mirrors the semantics of CosmosDBClient: documents are partitioned by
+// subscription ID, conditional writes check ETags, and callers receive copies of stored documents.
 type Cache struct {
-	cluster      map[string]*HCPOpenShiftClusterDocument
-	nodePool     map[string]*NodePoolDocument
-	subscription map[string]*SubscriptionDocument
+	mu sync.RWMutex
+	// path, if set, is the file the cache contents are saved to after every write
+	path  string
+	state cacheState
+}
+
+// cacheState holds the contents of a Cache. Documents are keyed by partition key, then by resource ID or document
+// ID. It is also the format of the persistence file.
+type cacheState struct {
+	Clusters      map[string]map[string]*HCPOpenShiftClusterDocument `json:"clusters"`
+	NodePools     map[string]map[string]*NodePoolDocument            `json:"nodePools"`
+	Subscriptions map[string]*SubscriptionDocument                   `json:"subscriptions"`
+	Operations    map[string]map[string]*OperationDocument           `json:"operations"`
+	Billing       map[string]map[string]*BillingDocument             `json:"billing"`
+	Leases        map[string]*LeaseDocument                          `json:"leases"`
 }
 
 // NewCache initializes a new Cache to allow for simple tests without needing a real CosmosDB. For production, use
 // NewCosmosDBConfig instead.
 func NewCache() DBClient {
-	return &Cache{
-		cluster:      make(map[string]*HCPOpenShiftClusterDocument),
-		nodePool:     make(map[string]*NodePoolDocument),
-		subscription: make(map[string]*SubscriptionDocument),
+	c := &Cache{}
+	c.state.init()
+	return c
+}
+
+// NewPersistentCache initializes a Cache that saves its contents to a JSON file at path, so local development state
+// survives restarts. Existing contents of the file are loaded.
+func NewPersistentCache(path string) (DBClient, error) {
+	c := &Cache{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &c.state); err != nil {
+			return nil, err
+		}
+	}
+	c.state.init()
+
+	return c, nil
+}
+
+func (s *cacheState) init() {
+	if s.Clusters == nil {
+		s.Clusters = make(map[string]map[string]*HCPOpenShiftClusterDocument)
+	}
+	if s.NodePools == nil {
+		s.NodePools = make(map[string]map[string]*NodePoolDocument)
+	}
+	if s.Subscriptions == nil {
+		s.Subscriptions = make(map[string]*SubscriptionDocument)
+	}
+	if s.Operations == nil {
+		s.Operations = make(map[string]map[string]*OperationDocument)
+	}
+	if s.Billing == nil {
+		s.Billing = make(map[string]map[string]*BillingDocument)
+	}
+	if s.Leases == nil {
+		s.Leases = make(map[string]*LeaseDocument)
+	}
+}
+
+// save writes the cache contents to the persistence file, if any. The
+// caller must hold the write lock.
+func (c *Cache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(&c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a
+	// partially written file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// clone returns a deep copy of a document so callers never share
+// memory with the cache, just as with documents read from Cosmos.
+func clone[T any](doc *T) (*T, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var out *T
+	if err = json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// partition returns the documents in a partition, creating it if needed.
+func partition[T any](m map[string]map[string]*T, partitionKey string) map[string]*T {
+	if m[partitionKey] == nil {
+		m[partitionKey] = make(map[string]*T)
+	}
+	return m[partitionKey]
+}
+
+// get returns a copy of a document in a partition or ErrNotFound.
+func get[T any](m map[string]map[string]*T, partitionKey, key string) (*T, error) {
+	doc, ok := m[partitionKey][key]
+	if !ok {
+		return nil, ErrNotFound
 	}
+	return clone(doc)
+}
+
+// checkETag mimics a Cosmos conditional write. An empty ETag means the
+// document must not exist yet; otherwise it must match the stored one.
+func checkETag(exists bool, storedETag, etag, key string) error {
+	if (etag == "" && exists) || (etag != "" && (!exists || etag != storedETag)) {
+		return &ConflictError{Key: key}
+	}
+	return nil
 }
 
 func (c *Cache) DBConnectionTest(ctx context.Context) error {
@@ -29,50 +165,360 @@ func (c *Cache) DBConnectionTest(ctx context.Context) error {
 }
 
 func (c *Cache) GetClusterDoc(ctx context.Context, resourceID string, subscriptionID string) (*HCPOpenShiftClusterDocument, error) {
-	if _, ok := c.cluster[resourceID]; ok {
-		return c.cluster[resourceID], nil
-	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	return nil, ErrNotFound
+	return get(c.state.Clusters, subscriptionID, resourceID)
 }
 
 func (c *Cache) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error {
-	c.cluster[doc.Key] = doc
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := partition(c.state.Clusters, doc.PartitionKey)
+	stored, exists := docs[doc.Key]
+	var storedETag string
+	if exists {
+		storedETag = stored.ETag
+	}
+	if err := checkETag(exists, storedETag, doc.ETag, doc.Key); err != nil {
+		return err
+	}
+	if doc.ETag == "" {
+		doc.ID = documentID(doc.Key)
+	}
+
+	stored, err := clone(doc)
+	if err != nil {
+		return err
+	}
+	stored.ETag = uuid.New().String()
+	docs[doc.Key] = stored
+	doc.ETag = stored.ETag
+
+	return c.save()
 }
 
 func (c *Cache) DeleteClusterDoc(ctx context.Context, resourceID string, subscriptionID string) error {
-	delete(c.cluster, resourceID)
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.state.Clusters[subscriptionID][resourceID]; !ok {
+		return ErrNotFound
+	}
+	delete(c.state.Clusters[subscriptionID], resourceID)
+
+	return c.save()
 }
 
-func (c *Cache) GetNodePoolDoc(ctx context.Context, resourceID string) (*NodePoolDocument, error) {
-	if _, ok := c.nodePool[resourceID]; ok {
-		return c.nodePool[resourceID], nil
+func (c *Cache) ListClusterDocs(ctx context.Context, subscriptionID, resourceGroupName, location string) ([]*HCPOpenShiftClusterDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var docs []*HCPOpenShiftClusterDocument
+	for _, doc := range c.state.Clusters[subscriptionID] {
+		if resourceGroupName != "" && !strings.HasPrefix(doc.Key, resourceGroupKeyPrefix(subscriptionID, resourceGroupName)) {
+			continue
+		}
+		if location != "" && doc.Location != strings.ToLower(location) {
+			continue
+		}
+		doc, err := clone(doc)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
 	}
+	return docs, nil
+}
 
-	return nil, ErrNotFound
+// ListClusterDocsPage pages through the cluster documents in key order.
+// The continuation is the key of the last document returned.
+func (c *Cache) ListClusterDocsPage(ctx context.Context, subscriptionID, resourceGroupName, location string, pageSize int, continuation string) ([]*HCPOpenShiftClusterDocument, string, error) {
+	docs, err := c.ListClusterDocs(ctx, subscriptionID, resourceGroupName, location)
+	if err != nil {
+		return nil, "", err
+	}
+	slices.SortFunc(docs, func(a, b *HCPOpenShiftClusterDocument) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	start, _ := slices.BinarySearchFunc(docs, continuation, func(doc *HCPOpenShiftClusterDocument, key string) int {
+		return strings.Compare(doc.Key, key)
+	})
+	if start < len(docs) && continuation != "" && docs[start].Key == continuation {
+		start++
+	}
+
+	if pageSize < 1 {
+		pageSize = len(docs)
+	}
+	end := min(start+pageSize, len(docs))
+	var next string
+	if end < len(docs) {
+		next = docs[end-1].Key
+	}
+	return docs[start:end], next, nil
+}
+
+func (c *Cache) GetNodePoolDoc(ctx context.Context, resourceID string, subscriptionID string) (*NodePoolDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return get(c.state.NodePools, subscriptionID, resourceID)
 }
 
 func (c *Cache) SetNodePoolDoc(ctx context.Context, doc *NodePoolDocument) error {
-	c.nodePool[doc.Key] = doc
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := partition(c.state.NodePools, doc.PartitionKey)
+	stored, exists := docs[doc.Key]
+	var storedETag string
+	if exists {
+		storedETag = stored.ETag
+	}
+	if err := checkETag(exists, storedETag, doc.ETag, doc.Key); err != nil {
+		return err
+	}
+	if doc.ETag == "" {
+		doc.ID = documentID(doc.Key)
+	}
+
+	stored, err := clone(doc)
+	if err != nil {
+		return err
+	}
+	stored.ETag = uuid.New().String()
+	docs[doc.Key] = stored
+	doc.ETag = stored.ETag
+
+	return c.save()
 }
 
-func (c *Cache) DeleteNodePoolDoc(ctx context.Context, resourceID string) error {
-	delete(c.nodePool, resourceID)
-	return nil
+func (c *Cache) DeleteNodePoolDoc(ctx context.Context, resourceID string, subscriptionID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.state.NodePools[subscriptionID][resourceID]; !ok {
+		return ErrNotFound
+	}
+	delete(c.state.NodePools[subscriptionID], resourceID)
+
+	return c.save()
+}
+
+func (c *Cache) ListNodePoolDocs(ctx context.Context, clusterResourceID string, subscriptionID string) ([]*NodePoolDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var docs []*NodePoolDocument
+	for _, doc := range c.state.NodePools[subscriptionID] {
+		if !strings.HasPrefix(doc.Key, clusterKeyPrefix(clusterResourceID)) {
+			continue
+		}
+		doc, err := clone(doc)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
 }
 
 func (c *Cache) GetSubscriptionDoc(ctx context.Context, subscriptionID string) (*SubscriptionDocument, error) {
-	if _, ok := c.subscription[subscriptionID]; ok {
-		return c.subscription[subscriptionID], nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	doc, ok := c.state.Subscriptions[subscriptionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(doc)
+}
+
+func (c *Cache) SetSubscriptionDoc(ctx context.Context, doc *SubscriptionDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, err := clone(doc)
+	if err != nil {
+		return err
+	}
+	stored.ETag = uuid.New().String()
+	c.state.Subscriptions[doc.PartitionKey] = stored
+
+	return c.save()
+}
+
+func (c *Cache) CreateOperationDoc(ctx context.Context, doc *OperationDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := partition(c.state.Operations, doc.PartitionKey)
+	if _, exists := docs[doc.ID]; exists {
+		return &ConflictError{Key: doc.ID}
+	}
+
+	stored, err := clone(doc)
+	if err != nil {
+		return err
+	}
+	stored.ETag = uuid.New().String()
+	docs[doc.ID] = stored
+
+	return c.save()
+}
+
+func (c *Cache) GetOperationDoc(ctx context.Context, operationID string, subscriptionID string) (*OperationDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return get(c.state.Operations, subscriptionID, operationID)
+}
+
+func (c *Cache) UpdateOperationStatus(ctx context.Context, operationID string, subscriptionID string, status arm.ProvisioningState, operationError *arm.CloudErrorBody) (*OperationDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc, ok := c.state.Operations[subscriptionID][operationID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if doc.Status != status {
+		doc.LastTransitionTime = time.Now().UTC()
+	}
+	doc.Status = status
+	doc.Error = operationError
+	doc.ETag = uuid.New().String()
+
+	if err := c.save(); err != nil {
+		return nil, err
 	}
+	return clone(doc)
+}
+
+func (c *Cache) ListOperationDocs(ctx context.Context, resourceID string, subscriptionID string) ([]*OperationDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var docs []*OperationDocument
+	for _, doc := range c.state.Operations[subscriptionID] {
+		if doc.ExternalID == resourceID {
+			doc, err := clone(doc)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (c *Cache) ListActiveOperationDocs(ctx context.Context) ([]*OperationDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var docs []*OperationDocument
+	for _, partition := range c.state.Operations {
+		for _, doc := range partition {
+			if !doc.Status.IsTerminal() {
+				doc, err := clone(doc)
+				if err != nil {
+					return nil, err
+				}
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return docs, nil
+}
 
+func (c *Cache) CreateBillingDoc(ctx context.Context, doc *BillingDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := partition(c.state.Billing, doc.PartitionKey)
+	if _, exists := docs[doc.ID]; exists {
+		return &ConflictError{Key: doc.ID}
+	}
+
+	stored, err := clone(doc)
+	if err != nil {
+		return err
+	}
+	stored.ETag = uuid.New().String()
+	docs[doc.ID] = stored
+
+	return c.save()
+}
+
+// activeBillingDoc returns the stored billing document without a deletion
+// time for a resource. The caller must hold the lock.
+func (c *Cache) activeBillingDoc(resourceID string, subscriptionID string) (*BillingDocument, error) {
+	for _, doc := range c.state.Billing[subscriptionID] {
+		if doc.ExternalID == resourceID && doc.DeletionTime == nil {
+			return doc, nil
+		}
+	}
 	return nil, ErrNotFound
 }
 
-func (c *Cache) SetSubscriptionDoc(ctx context.Context, doc *SubscriptionDocument) error {
-	c.subscription[doc.PartitionKey] = doc
+func (c *Cache) GetBillingDoc(ctx context.Context, resourceID string, subscriptionID string) (*BillingDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	doc, err := c.activeBillingDoc(resourceID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return clone(doc)
+}
+
+func (c *Cache) MarkBillingDocDeleted(ctx context.Context, resourceID string, subscriptionID string, deletionTime time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc, err := c.activeBillingDoc(resourceID, subscriptionID)
+	if err != nil {
+		return err
+	}
+	deletionTime = deletionTime.UTC()
+	doc.DeletionTime = &deletionTime
+	doc.ETag = uuid.New().String()
+
+	return c.save()
+}
+
+func (c *Cache) AcquireLease(ctx context.Context, name string, holder string, duration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	if current, ok := c.state.Leases[name]; ok && current.Holder != holder && now.Before(current.Expires) {
+		return false, nil
+	}
+
+	c.state.Leases[name] = &LeaseDocument{
+		ID:           name,
+		PartitionKey: name,
+		Holder:       holder,
+		TTL:          int(duration.Seconds()),
+		Expires:      now.Add(duration),
+		ETag:         uuid.New().String(),
+	}
+	return true, c.save()
+}
+
+func (c *Cache) ReleaseLease(ctx context.Context, name string, holder string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.state.Leases[name]; ok && current.Holder == holder {
+		delete(c.state.Leases, name)
+		return c.save()
+	}
 	return nil
 }
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const testSubscriptionID = "00000000-0000-0000-0000-000000000000"
+
+func testClusterKey(name string) string {
+	return "/subscriptions/" + testSubscriptionID + "/resourcegroups/myrg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/" + name
+}
+
+func TestPersistentCache(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	dbClient, err := NewPersistentCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusterDoc := &HCPOpenShiftClusterDocument{
+		Key:          testClusterKey("mycluster"),
+		PartitionKey: testSubscriptionID,
+		ClusterID:    "cluster-id",
+	}
+	err = dbClient.SetClusterDoc(ctx, clusterDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operationDoc := NewOperationDocument(OperationRequestCreate, testSubscriptionID, clusterDoc.Key, clusterDoc.ClusterID)
+	err = dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the cache from the file as though the process restarted.
+	dbClient, err = NewPersistentCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dbClient.GetClusterDoc(ctx, clusterDoc.Key, testSubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClusterID != clusterDoc.ClusterID || got.ETag != clusterDoc.ETag {
+		t.Errorf("expected cluster %q with ETag %q, got %q with ETag %q", clusterDoc.ClusterID, clusterDoc.ETag, got.ClusterID, got.ETag)
+	}
+
+	docs, err := dbClient.ListActiveOperationDocs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].ID != operationDoc.ID {
+		t.Errorf("expected operation %s to survive a restart, got %d active operations", operationDoc.ID, len(docs))
+	}
+
+	// A stale ETag must still be rejected after a restart.
+	clusterDoc.ETag = "stale"
+	err = dbClient.SetClusterDoc(ctx, clusterDoc)
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+}
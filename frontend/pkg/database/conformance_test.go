@@ -0,0 +1,926 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/google/uuid"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// Environment variables that select a Cosmos DB account, such as the Cosmos
+// DB emulator, to run the DBClient conformance tests against. The account
+// must already contain the database and its containers. If no key is given
+// the default Azure credential is used.
+const (
+	cosmosTestURLEnv    = "COSMOS_TEST_URL"
+	cosmosTestDBNameEnv = "COSMOS_TEST_DB_NAME"
+	cosmosTestKeyEnv    = "COSMOS_TEST_KEY"
+)
+
+// testDBClient runs a conformance suite that asserts every DBClient
+// implementation behaves the same. The newDBClient function is called
+// once per subtest. Documents are created under random subscription IDs
+// so the suite can run against a shared database.
+func testDBClient(t *testing.T, newDBClient func(t *testing.T) DBClient) {
+	t.Run("ClusterDocs", func(t *testing.T) {
+		testClusterDocs(t, newDBClient(t))
+	})
+	t.Run("ListClusterDocs", func(t *testing.T) {
+		testListClusterDocs(t, newDBClient(t))
+	})
+	t.Run("ListClusterDocsPage", func(t *testing.T) {
+		testListClusterDocsPage(t, newDBClient(t))
+	})
+	t.Run("NodePoolDocs", func(t *testing.T) {
+		testNodePoolDocs(t, newDBClient(t))
+	})
+	t.Run("ListNodePoolDocs", func(t *testing.T) {
+		testListNodePoolDocs(t, newDBClient(t))
+	})
+	t.Run("SubscriptionDocs", func(t *testing.T) {
+		testSubscriptionDocs(t, newDBClient(t))
+	})
+	t.Run("OperationDocs", func(t *testing.T) {
+		testOperationDocs(t, newDBClient(t))
+	})
+	t.Run("ActiveOperationDocs", func(t *testing.T) {
+		testActiveOperationDocs(t, newDBClient(t))
+	})
+	t.Run("BillingDocs", func(t *testing.T) {
+		testBillingDocs(t, newDBClient(t))
+	})
+	t.Run("Leases", func(t *testing.T) {
+		testLeases(t, newDBClient(t))
+	})
+	t.Run("PartitionIsolation", func(t *testing.T) {
+		testPartitionIsolation(t, newDBClient(t))
+	})
+	t.Run("Concurrency", func(t *testing.T) {
+		testConcurrency(t, newDBClient(t))
+	})
+}
+
+func TestCacheConformance(t *testing.T) {
+	testDBClient(t, func(t *testing.T) DBClient {
+		return NewCache()
+	})
+}
+
+func TestPersistentCacheConformance(t *testing.T) {
+	testDBClient(t, func(t *testing.T) DBClient {
+		dbClient, err := NewPersistentCache(filepath.Join(t.TempDir(), "cache.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dbClient
+	})
+}
+
+func TestCosmosDBClientConformance(t *testing.T) {
+	dbURL := os.Getenv(cosmosTestURLEnv)
+	dbName := os.Getenv(cosmosTestDBNameEnv)
+	if dbURL == "" || dbName == "" {
+		t.Skipf("%s and %s are not set", cosmosTestURLEnv, cosmosTestDBNameEnv)
+	}
+
+	testDBClient(t, func(t *testing.T) DBClient {
+		config := NewCosmosDBConfig(dbName, dbURL)
+
+		key := os.Getenv(cosmosTestKeyEnv)
+		if key == "" {
+			dbClient, err := NewCosmosDBClient(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return dbClient
+		}
+
+		cred, err := azcosmos.NewKeyCredential(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client, err := azcosmos.NewClientWithKey(dbURL, cred, newCosmosClientOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &CosmosDBClient{client: client, config: config}
+	})
+}
+
+func newTestSubscriptionID() string {
+	return uuid.New().String()
+}
+
+func newTestClusterDoc(subscriptionID, name string) *HCPOpenShiftClusterDocument {
+	return newTestClusterDocIn(subscriptionID, "myRG", "eastus", name)
+}
+
+func newTestClusterDocIn(subscriptionID, resourceGroupName, location, name string) *HCPOpenShiftClusterDocument {
+	return &HCPOpenShiftClusterDocument{
+		ID:           uuid.New().String(),
+		Key:          strings.ToLower("/subscriptions/" + subscriptionID + "/resourceGroups/" + resourceGroupName + "/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/" + name),
+		PartitionKey: subscriptionID,
+		ClusterID:    uuid.New().String(),
+		Location:     location,
+	}
+}
+
+func newTestNodePoolDoc(clusterDoc *HCPOpenShiftClusterDocument, name string) *NodePoolDocument {
+	return &NodePoolDocument{
+		ID:           uuid.New().String(),
+		Key:          clusterDoc.Key + "/nodepools/" + name,
+		PartitionKey: clusterDoc.PartitionKey,
+		NodePoolID:   uuid.New().String(),
+	}
+}
+
+func expectNotFound(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func expectConflict(t *testing.T, err error) {
+	t.Helper()
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+}
+
+func testClusterDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	doc := newTestClusterDoc(subscriptionID, "mycluster")
+
+	_, err := dbClient.GetClusterDoc(ctx, doc.Key, subscriptionID)
+	expectNotFound(t, err)
+
+	err = dbClient.SetClusterDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ETag == "" {
+		t.Fatal("expected an ETag after creating the document")
+	}
+	createdETag := doc.ETag
+
+	got, err := dbClient.GetClusterDoc(ctx, doc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClusterID != doc.ClusterID || got.ETag != doc.ETag {
+		t.Errorf("expected cluster %q with ETag %q, got %q with ETag %q", doc.ClusterID, doc.ETag, got.ClusterID, got.ETag)
+	}
+
+	// Documents returned to the caller must not alias stored documents.
+	got.ProvisioningState = arm.ProvisioningStateFailed
+	got, err = dbClient.GetClusterDoc(ctx, doc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ProvisioningState == arm.ProvisioningStateFailed {
+		t.Error("modifying a returned document changed the stored document")
+	}
+
+	// Creating the same document again must fail.
+	recreate := *doc
+	recreate.ETag = ""
+	expectConflict(t, dbClient.SetClusterDoc(ctx, &recreate))
+
+	// So must creating another document for the same resource, such as
+	// from a concurrent first-time request.
+	duplicate := *doc
+	duplicate.ID = uuid.New().String()
+	duplicate.ETag = ""
+	expectConflict(t, dbClient.SetClusterDoc(ctx, &duplicate))
+
+	doc.ProvisioningState = arm.ProvisioningStateSucceeded
+	err = dbClient.SetClusterDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ETag == createdETag {
+		t.Error("expected the ETag to change after updating the document")
+	}
+
+	// Updating with the stale ETag must fail.
+	stale := *doc
+	stale.ETag = createdETag
+	expectConflict(t, dbClient.SetClusterDoc(ctx, &stale))
+
+	docs, err := dbClient.ListClusterDocs(ctx, subscriptionID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Key != doc.Key || docs[0].ProvisioningState != arm.ProvisioningStateSucceeded {
+		t.Errorf("expected the updated document for %s, got %d documents", doc.Key, len(docs))
+	}
+
+	err = dbClient.DeleteClusterDoc(ctx, doc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dbClient.GetClusterDoc(ctx, doc.Key, subscriptionID)
+	expectNotFound(t, err)
+	expectNotFound(t, dbClient.DeleteClusterDoc(ctx, doc.Key, subscriptionID))
+
+	// Updating a deleted document must fail.
+	expectConflict(t, dbClient.SetClusterDoc(ctx, doc))
+
+	docs, err = dbClient.ListClusterDocs(ctx, subscriptionID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 0 {
+		t.Errorf("expected no documents, got %d", len(docs))
+	}
+}
+
+func testListClusterDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+
+	docs := []*HCPOpenShiftClusterDocument{
+		newTestClusterDocIn(subscriptionID, "rg1", "eastus", "cluster1"),
+		newTestClusterDocIn(subscriptionID, "rg1", "westus", "cluster2"),
+		newTestClusterDocIn(subscriptionID, "rg2", "eastus", "cluster3"),
+		// A resource group whose name starts with another's.
+		newTestClusterDocIn(subscriptionID, "rg10", "eastus", "cluster4"),
+	}
+	for _, doc := range docs {
+		err := dbClient.SetClusterDoc(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		resourceGroupName string
+		location          string
+		expected          []string
+	}{
+		{
+			name:     "Subscription",
+			expected: []string{"cluster1", "cluster2", "cluster3", "cluster4"},
+		},
+		{
+			name:              "Resource group",
+			resourceGroupName: "RG1",
+			expected:          []string{"cluster1", "cluster2"},
+		},
+		{
+			name:     "Location",
+			location: "EastUS",
+			expected: []string{"cluster1", "cluster3", "cluster4"},
+		},
+		{
+			name:              "Resource group and location",
+			resourceGroupName: "rg1",
+			location:          "westus",
+			expected:          []string{"cluster2"},
+		},
+		{
+			name:              "Empty resource group",
+			resourceGroupName: "rg3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := dbClient.ListClusterDocs(ctx, subscriptionID, test.resourceGroupName, test.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, doc := range list {
+				names = append(names, path.Base(doc.Key))
+			}
+			slices.Sort(names)
+
+			if !slices.Equal(names, test.expected) {
+				t.Errorf("expected clusters %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func testListClusterDocsPage(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+
+	var expected []string
+	for _, name := range []string{"cluster1", "cluster2", "cluster3", "cluster4", "cluster5"} {
+		doc := newTestClusterDocIn(subscriptionID, "rg1", "eastus", name)
+		err := dbClient.SetClusterDoc(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, name)
+	}
+	err := dbClient.SetClusterDoc(ctx, newTestClusterDocIn(subscriptionID, "rg2", "eastus", "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var continuation string
+	for pages := 1; ; pages++ {
+		if pages > len(expected) {
+			t.Fatal("too many pages")
+		}
+
+		var docs []*HCPOpenShiftClusterDocument
+		docs, continuation, err = dbClient.ListClusterDocsPage(ctx, subscriptionID, "rg1", "", 2, continuation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(docs) > 2 {
+			t.Errorf("expected at most 2 documents per page, got %d", len(docs))
+		}
+		for _, doc := range docs {
+			names = append(names, path.Base(doc.Key))
+		}
+		if continuation == "" {
+			break
+		}
+	}
+	slices.Sort(names)
+
+	if !slices.Equal(names, expected) {
+		t.Errorf("expected clusters %v, got %v", expected, names)
+	}
+}
+
+func testNodePoolDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	doc := newTestNodePoolDoc(newTestClusterDoc(subscriptionID, "mycluster"), "mynodepool")
+
+	_, err := dbClient.GetNodePoolDoc(ctx, doc.Key, subscriptionID)
+	expectNotFound(t, err)
+
+	err = dbClient.SetNodePoolDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdETag := doc.ETag
+
+	got, err := dbClient.GetNodePoolDoc(ctx, doc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NodePoolID != doc.NodePoolID || got.ETag != doc.ETag {
+		t.Errorf("expected node pool %q with ETag %q, got %q with ETag %q", doc.NodePoolID, doc.ETag, got.NodePoolID, got.ETag)
+	}
+
+	recreate := *doc
+	recreate.ETag = ""
+	expectConflict(t, dbClient.SetNodePoolDoc(ctx, &recreate))
+
+	duplicate := *doc
+	duplicate.ID = uuid.New().String()
+	duplicate.ETag = ""
+	expectConflict(t, dbClient.SetNodePoolDoc(ctx, &duplicate))
+
+	doc.ProvisioningState = arm.ProvisioningStateSucceeded
+	err = dbClient.SetNodePoolDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := *doc
+	stale.ETag = createdETag
+	expectConflict(t, dbClient.SetNodePoolDoc(ctx, &stale))
+
+	err = dbClient.DeleteNodePoolDoc(ctx, doc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dbClient.GetNodePoolDoc(ctx, doc.Key, subscriptionID)
+	expectNotFound(t, err)
+	expectNotFound(t, dbClient.DeleteNodePoolDoc(ctx, doc.Key, subscriptionID))
+}
+
+func testListNodePoolDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	clusterDoc := newTestClusterDoc(subscriptionID, "mycluster")
+
+	for _, doc := range []*NodePoolDocument{
+		newTestNodePoolDoc(clusterDoc, "np1"),
+		newTestNodePoolDoc(clusterDoc, "np2"),
+		newTestNodePoolDoc(newTestClusterDoc(subscriptionID, "mycluster2"), "np1"),
+	} {
+		err := dbClient.SetNodePoolDoc(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docs, err := dbClient.ListNodePoolDocs(ctx, strings.ToUpper(clusterDoc.Key), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, doc := range docs {
+		keys = append(keys, doc.Key)
+	}
+	slices.Sort(keys)
+	expected := []string{clusterDoc.Key + "/nodepools/np1", clusterDoc.Key + "/nodepools/np2"}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("expected node pools %v, got %v", expected, keys)
+	}
+
+	docs, err = dbClient.ListNodePoolDocs(ctx, clusterDoc.Key, newTestSubscriptionID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 0 {
+		t.Errorf("expected no node pools in another subscription, got %d", len(docs))
+	}
+}
+
+func testSubscriptionDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+
+	_, err := dbClient.GetSubscriptionDoc(ctx, subscriptionID)
+	expectNotFound(t, err)
+
+	doc := &SubscriptionDocument{
+		ID:           uuid.New().String(),
+		PartitionKey: subscriptionID,
+		Subscription: &arm.Subscription{State: arm.Registered},
+	}
+	err = dbClient.SetSubscriptionDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscription documents are upserted.
+	doc.Subscription.State = arm.Warned
+	err = dbClient.SetSubscriptionDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dbClient.GetSubscriptionDoc(ctx, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != doc.ID || got.Subscription == nil || got.Subscription.State != arm.Warned {
+		t.Errorf("expected subscription document %s in state %s, got %+v", doc.ID, arm.Warned, got)
+	}
+}
+
+func testOperationDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	clusterDoc := newTestClusterDoc(subscriptionID, "mycluster")
+
+	_, err := dbClient.GetOperationDoc(ctx, uuid.New().String(), subscriptionID)
+	expectNotFound(t, err)
+	_, err = dbClient.UpdateOperationStatus(ctx, uuid.New().String(), subscriptionID, arm.ProvisioningStateSucceeded, nil)
+	expectNotFound(t, err)
+
+	createDoc := NewOperationDocument(OperationRequestCreate, subscriptionID, clusterDoc.Key, clusterDoc.ClusterID)
+	err = dbClient.CreateOperationDoc(ctx, createDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectConflict(t, dbClient.CreateOperationDoc(ctx, createDoc))
+
+	updateDoc := NewOperationDocument(OperationRequestUpdate, subscriptionID, clusterDoc.Key, clusterDoc.ClusterID)
+	err = dbClient.CreateOperationDoc(ctx, updateDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherDoc := NewOperationDocument(OperationRequestCreate, subscriptionID, clusterDoc.Key+"-other", "")
+	err = dbClient.CreateOperationDoc(ctx, otherDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dbClient.GetOperationDoc(ctx, createDoc.ID, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Request != OperationRequestCreate || got.ExternalID != clusterDoc.Key || got.Status != arm.ProvisioningStateAccepted {
+		t.Errorf("unexpected operation document %+v", got)
+	}
+
+	docs, err := dbClient.ListOperationDocs(ctx, clusterDoc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected 2 operations for %s, got %d", clusterDoc.Key, len(docs))
+	}
+
+	operationError := &arm.CloudErrorBody{Code: arm.CloudErrorCodeInternalServerError, Message: "failed"}
+	updated, err := dbClient.UpdateOperationStatus(ctx, createDoc.ID, subscriptionID, arm.ProvisioningStateFailed, operationError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != arm.ProvisioningStateFailed || updated.Error == nil || updated.Error.Message != operationError.Message {
+		t.Errorf("unexpected updated operation document %+v", updated)
+	}
+	if updated.LastTransitionTime.Before(createDoc.LastTransitionTime) {
+		t.Error("expected the last transition time to advance")
+	}
+
+	got, err = dbClient.GetOperationDoc(ctx, createDoc.ID, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != arm.ProvisioningStateFailed {
+		t.Errorf("expected status %s, got %s", arm.ProvisioningStateFailed, got.Status)
+	}
+	// A conditional write with the returned document must match the
+	// stored ETag.
+	if updated.ETag == "" || updated.ETag != got.ETag {
+		t.Errorf("expected the returned ETag %q to match the stored ETag %q", updated.ETag, got.ETag)
+	}
+
+	// The active operation list spans all subscriptions, so only
+	// consider the operations created by this test.
+	active, err := dbClient.ListActiveOperationDocs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeIDs := make(map[string]bool)
+	for _, doc := range active {
+		if doc.PartitionKey == subscriptionID {
+			activeIDs[doc.ID] = true
+		}
+	}
+	if len(activeIDs) != 2 || !activeIDs[updateDoc.ID] || !activeIDs[otherDoc.ID] {
+		t.Errorf("expected operations %s and %s to be active, got %v", updateDoc.ID, otherDoc.ID, activeIDs)
+	}
+}
+
+// testActiveOperationDocs pins ListActiveOperationDocs returning active
+// operations from every subscription. Against Cosmos DB this exercises
+// crossPartitionQueryPolicy, which relies on how the azcosmos module and
+// the gateway handle partition key headers.
+func testActiveOperationDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+
+	var subscriptionIDs []string
+	expected := make(map[string]bool)
+	for range 3 {
+		subscriptionID := newTestSubscriptionID()
+		subscriptionIDs = append(subscriptionIDs, subscriptionID)
+		clusterDoc := newTestClusterDoc(subscriptionID, "mycluster")
+
+		activeDoc := NewOperationDocument(OperationRequestCreate, subscriptionID, clusterDoc.Key, clusterDoc.ClusterID)
+		err := dbClient.CreateOperationDoc(ctx, activeDoc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[activeDoc.ID] = true
+
+		completedDoc := NewOperationDocument(OperationRequestUpdate, subscriptionID, clusterDoc.Key, clusterDoc.ClusterID)
+		err = dbClient.CreateOperationDoc(ctx, completedDoc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = dbClient.UpdateOperationStatus(ctx, completedDoc.ID, subscriptionID, arm.ProvisioningStateSucceeded, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The active operation list spans all subscriptions, so only
+	// consider the operations created by this test.
+	active, err := dbClient.ListActiveOperationDocs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, doc := range active {
+		if slices.Contains(subscriptionIDs, doc.PartitionKey) {
+			got[doc.ID] = true
+		}
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d active operations, got %d", len(expected), len(got))
+	}
+	for id := range expected {
+		if !got[id] {
+			t.Errorf("expected operation %s to be active", id)
+		}
+	}
+
+	// Queries scoped to a subscription stay scoped to it.
+	docs, err := dbClient.ListOperationDocs(ctx, newTestClusterDoc(subscriptionIDs[0], "mycluster").Key, subscriptionIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected 2 operations in subscription %s, got %d", subscriptionIDs[0], len(docs))
+	}
+}
+
+func testBillingDocs(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	clusterDoc := newTestClusterDoc(subscriptionID, "mycluster")
+	creationTime := time.Now().UTC().Truncate(time.Second)
+
+	_, err := dbClient.GetBillingDoc(ctx, clusterDoc.Key, subscriptionID)
+	expectNotFound(t, err)
+	expectNotFound(t, dbClient.MarkBillingDocDeleted(ctx, clusterDoc.Key, subscriptionID, creationTime))
+
+	doc := NewBillingDocument(subscriptionID, uuid.New().String(), "eastus", clusterDoc.Key, creationTime)
+	err = dbClient.CreateBillingDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectConflict(t, dbClient.CreateBillingDoc(ctx, doc))
+
+	got, err := dbClient.GetBillingDoc(ctx, clusterDoc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != doc.ID || !got.CreationTime.Equal(creationTime) || got.DeletionTime != nil {
+		t.Errorf("unexpected billing document %+v", got)
+	}
+
+	err = dbClient.MarkBillingDocDeleted(ctx, clusterDoc.Key, subscriptionID, creationTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only billing documents without a deletion time are returned.
+	_, err = dbClient.GetBillingDoc(ctx, clusterDoc.Key, subscriptionID)
+	expectNotFound(t, err)
+
+	// A cluster recreated with the same resource ID gets a new document.
+	err = dbClient.CreateBillingDoc(ctx, NewBillingDocument(subscriptionID, doc.TenantID, doc.Location, clusterDoc.Key, creationTime.Add(2*time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = dbClient.GetBillingDoc(ctx, clusterDoc.Key, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID == doc.ID {
+		t.Error("expected the billing document of the recreated cluster")
+	}
+}
+
+func testLeases(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	name := "lease-" + uuid.New().String()
+
+	acquired, err := dbClient.AcquireLease(ctx, name, "holder1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Fatal("expected holder1 to acquire the lease")
+	}
+
+	acquired, err = dbClient.AcquireLease(ctx, name, "holder2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acquired {
+		t.Error("expected holder2 not to acquire a lease held by holder1")
+	}
+
+	acquired, err = dbClient.AcquireLease(ctx, name, "holder1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Error("expected holder1 to renew the lease")
+	}
+
+	// Releasing a lease held by another holder does nothing.
+	err = dbClient.ReleaseLease(ctx, name, "holder2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired, err = dbClient.AcquireLease(ctx, name, "holder2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acquired {
+		t.Error("expected holder2 not to acquire the lease after releasing it as a non-holder")
+	}
+
+	err = dbClient.ReleaseLease(ctx, name, "holder1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired, err = dbClient.AcquireLease(ctx, name, "holder2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Error("expected holder2 to acquire the released lease")
+	}
+
+	err = dbClient.ReleaseLease(ctx, name, "holder2")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testPartitionIsolation(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	otherSubscriptionID := newTestSubscriptionID()
+
+	clusterDoc := newTestClusterDoc(subscriptionID, "mycluster")
+	err := dbClient.SetClusterDoc(ctx, clusterDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodePoolDoc := newTestNodePoolDoc(clusterDoc, "mynodepool")
+	err = dbClient.SetNodePoolDoc(ctx, nodePoolDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operationDoc := NewOperationDocument(OperationRequestCreate, subscriptionID, clusterDoc.Key, clusterDoc.ClusterID)
+	err = dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbClient.CreateBillingDoc(ctx, NewBillingDocument(subscriptionID, uuid.New().String(), "eastus", clusterDoc.Key, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbClient.SetSubscriptionDoc(ctx, &SubscriptionDocument{
+		ID:           uuid.New().String(),
+		PartitionKey: subscriptionID,
+		Subscription: &arm.Subscription{State: arm.Registered},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// None of the documents are visible from another subscription.
+	_, err = dbClient.GetClusterDoc(ctx, clusterDoc.Key, otherSubscriptionID)
+	expectNotFound(t, err)
+	_, err = dbClient.GetNodePoolDoc(ctx, nodePoolDoc.Key, otherSubscriptionID)
+	expectNotFound(t, err)
+	_, err = dbClient.GetOperationDoc(ctx, operationDoc.ID, otherSubscriptionID)
+	expectNotFound(t, err)
+	_, err = dbClient.GetBillingDoc(ctx, clusterDoc.Key, otherSubscriptionID)
+	expectNotFound(t, err)
+	_, err = dbClient.GetSubscriptionDoc(ctx, otherSubscriptionID)
+	expectNotFound(t, err)
+	expectNotFound(t, dbClient.DeleteClusterDoc(ctx, clusterDoc.Key, otherSubscriptionID))
+	expectNotFound(t, dbClient.DeleteNodePoolDoc(ctx, nodePoolDoc.Key, otherSubscriptionID))
+
+	clusterDocs, err := dbClient.ListClusterDocs(ctx, otherSubscriptionID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusterDocs) != 0 {
+		t.Errorf("expected no cluster documents in another subscription, got %d", len(clusterDocs))
+	}
+
+	operationDocs, err := dbClient.ListOperationDocs(ctx, clusterDoc.Key, otherSubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operationDocs) != 0 {
+		t.Errorf("expected no operation documents in another subscription, got %d", len(operationDocs))
+	}
+
+	// The documents are untouched in their own subscription.
+	_, err = dbClient.GetClusterDoc(ctx, clusterDoc.Key, subscriptionID)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = dbClient.GetNodePoolDoc(ctx, nodePoolDoc.Key, subscriptionID)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func testConcurrency(t *testing.T, dbClient DBClient) {
+	ctx := context.Background()
+	subscriptionID := newTestSubscriptionID()
+	const writers = 10
+
+	doc := newTestClusterDoc(subscriptionID, "mycluster")
+	err := dbClient.SetClusterDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent updates from the same ETag: exactly one must win.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded, conflicted int
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			update := *doc
+			update.ProvisioningState = arm.ProvisioningStateUpdating
+			err := dbClient.SetClusterDoc(ctx, &update)
+
+			var conflictErr *ConflictError
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.As(err, &conflictErr):
+				conflicted++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 || conflicted != writers-1 {
+		t.Errorf("expected 1 update to succeed and %d to conflict, got %d and %d", writers-1, succeeded, conflicted)
+	}
+
+	// Concurrent lease acquisition: exactly one holder must win.
+	name := "lease-" + uuid.New().String()
+	var holders int
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			acquired, err := dbClient.AcquireLease(ctx, name, uuid.New().String(), time.Minute)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if acquired {
+				mu.Lock()
+				holders++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if holders != 1 {
+		t.Errorf("expected exactly 1 holder of the lease, got %d", holders)
+	}
+
+	// Concurrent writes to distinct documents all succeed.
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			doc := newTestClusterDoc(subscriptionID, uuid.New().String())
+			if err := dbClient.SetClusterDoc(ctx, doc); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := dbClient.GetClusterDoc(ctx, doc.Key, subscriptionID); err != nil {
+				t.Error(err)
+			}
+			if _, err := dbClient.ListClusterDocs(ctx, subscriptionID, "", ""); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	docs, err := dbClient.ListClusterDocs(ctx, subscriptionID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != writers+1 {
+		t.Errorf("expected %d cluster documents, got %d", writers+1, len(docs))
+	}
+}
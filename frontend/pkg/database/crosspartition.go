@@ -0,0 +1,64 @@
+package database
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	cosmosHeaderPartitionKey              = "x-ms-documentdb-partitionkey"
+	cosmosHeaderEnableCrossPartitionQuery = "x-ms-documentdb-query-enablecrosspartition"
+
+	// cosmosContentTypeQuery is the content type of a query request
+	cosmosContentTypeQuery = "application/query+json"
+	// cosmosNullPartitionKey is the header value for azcosmos.NullPartitionKey
+	cosmosNullPartitionKey = "[null]"
+)
+
+type contextKeyCrossPartitionQuery struct{}
+
+// withCrossPartitionQuery marks a query request context for execution
+// across all partitions of a container. Only use it for a single query
+// against azcosmos.NullPartitionKey, and do not pass the returned context
+// on to any other request.
+func withCrossPartitionQuery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyCrossPartitionQuery{}, true)
+}
+
+// crossPartitionQueryPolicy rewrites a query request marked with
+// withCrossPartitionQuery to span all partitions. The azcosmos module
+// always scopes a query to a single partition key, but the Cosmos gateway
+// can fan out simple queries (no aggregates or ORDER BY) when asked to.
+//
+// Any other request passes through unchanged, including a marked request
+// that is not a query against azcosmos.NullPartitionKey, so the policy
+// never widens a request that names a real partition.
+//
+// The policy depends on azcosmos internals, so the DBClient conformance
+// suite pins its behavior against Cosmos DB. Run it against the emulator
+// when upgrading the azcosmos module.
+type crossPartitionQueryPolicy struct{}
+
+func (p *crossPartitionQueryPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if isCrossPartitionQuery(req.Raw()) {
+		req.Raw().Header.Del(cosmosHeaderPartitionKey)
+		req.Raw().Header.Set(cosmosHeaderEnableCrossPartitionQuery, "True")
+	}
+	return req.Next()
+}
+
+// isCrossPartitionQuery returns true if the request is a query against
+// azcosmos.NullPartitionKey whose context is marked with
+// withCrossPartitionQuery.
+func isCrossPartitionQuery(req *http.Request) bool {
+	crossPartition, _ := req.Context().Value(contextKeyCrossPartitionQuery{}).(bool)
+	return crossPartition &&
+		req.Method == http.MethodPost &&
+		req.Header.Get("Content-Type") == cosmosContentTypeQuery &&
+		req.Header.Get(cosmosHeaderPartitionKey) == cosmosNullPartitionKey
+}
@@ -0,0 +1,91 @@
+package database
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+type transporterFunc func(*http.Request) (*http.Response, error)
+
+func (f transporterFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCrossPartitionQueryPolicy(t *testing.T) {
+	tests := []struct {
+		name                 string
+		marked               bool
+		contentType          string
+		partitionKey         string
+		expectCrossPartition bool
+	}{
+		{
+			name:                 "Marked query against the null partition key",
+			marked:               true,
+			contentType:          cosmosContentTypeQuery,
+			partitionKey:         cosmosNullPartitionKey,
+			expectCrossPartition: true,
+		},
+		{
+			name:         "Unmarked query against the null partition key",
+			contentType:  cosmosContentTypeQuery,
+			partitionKey: cosmosNullPartitionKey,
+		},
+		{
+			name:         "Marked query against a partition",
+			marked:       true,
+			contentType:  cosmosContentTypeQuery,
+			partitionKey: `["00000000-0000-0000-0000-000000000000"]`,
+		},
+		{
+			name:         "Marked write against the null partition key",
+			marked:       true,
+			contentType:  "application/json",
+			partitionKey: cosmosNullPartitionKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent *http.Request
+			pipeline := runtime.NewPipeline("test", "v0.0.0",
+				runtime.PipelineOptions{PerCall: []policy.Policy{&crossPartitionQueryPolicy{}}},
+				&policy.ClientOptions{Transport: transporterFunc(func(req *http.Request) (*http.Response, error) {
+					sent = req
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+				})})
+
+			ctx := context.Background()
+			if tt.marked {
+				ctx = withCrossPartitionQuery(ctx)
+			}
+
+			req, err := runtime.NewRequest(ctx, http.MethodPost, "https://example.documents.azure.com/dbs/db/colls/container/docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Raw().Header.Set("Content-Type", tt.contentType)
+			req.Raw().Header.Set(cosmosHeaderPartitionKey, tt.partitionKey)
+
+			if _, err = pipeline.Do(req); err != nil {
+				t.Fatal(err)
+			}
+
+			crossPartition := sent.Header.Get(cosmosHeaderEnableCrossPartitionQuery) != ""
+			if crossPartition != tt.expectCrossPartition {
+				t.Errorf("expected cross-partition query %t, got %t", tt.expectCrossPartition, crossPartition)
+			}
+			keptPartitionKey := sent.Header.Get(cosmosHeaderPartitionKey) == tt.partitionKey
+			if keptPartitionKey == tt.expectCrossPartition {
+				t.Errorf("expected partition key header kept %t, got %q", !tt.expectCrossPartition, sent.Header.Get(cosmosHeaderPartitionKey))
+			}
+		})
+	}
+}
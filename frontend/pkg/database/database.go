@@ -2,12 +2,21 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
 
 const (
@@ -16,10 +25,23 @@ const (
 	subsContainer      = "Subscriptions"
 	billingContainer   = "Billing"
 	asyncContainer     = "AsyncOperations"
+	locksContainer     = "Locks"
 )
 
 var ErrNotFound = errors.New("DocumentNotFound")
 
+// ConflictError is returned by a conditional write when the stored document
+// no longer matches the document's ETag, meaning another writer created,
+// modified or deleted it since it was read.
+type ConflictError struct {
+	// Key is the resource ID of the conflicting document
+	Key string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("document for %s was changed by another request", e.Key)
+}
+
 // DBClient is a document store for frontend to perform required CRUD operations against
 type DBClient interface {
 	// DBConnectionTest is used to health check the database. If the database is not reachable or otherwise not ready
@@ -29,19 +51,76 @@ type DBClient interface {
 	// GetClusterDoc retrieves an HCPOpenShiftClusterDocument from the database given its resourceID and containing
 	// subscriptionID. ErrNotFound is returned if an associated HCPOpenShiftClusterDocument cannot be found.
 	GetClusterDoc(ctx context.Context, resourceID string, subscriptionID string) (*HCPOpenShiftClusterDocument, error)
+	// SetClusterDoc conditionally writes an HCPOpenShiftClusterDocument to the database. If the document has an ETag,
+	// the write only succeeds if the stored document still has that ETag. Otherwise the document is created with an
+	// ID derived from its Key, so there is at most one document per resource. A *ConflictError is returned if the
+	// condition is not met. On success the document's ETag is updated.
 	SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error
 	// DeleteClusterDoc deletes an HCPOpenShiftClusterDocument from the database given the resourceID and containing
-	// subscriptionID of a Microsoft.RedHatOpenshift/HcpOpenShiftClusters resource.
+	// subscriptionID of a Microsoft.RedHatOpenshift/HcpOpenShiftClusters resource. ErrNotFound is returned if the
+	// HCPOpenShiftClusterDocument does not exist.
 	DeleteClusterDoc(ctx context.Context, resourceID string, subscriptionID string) error
-
-	GetNodePoolDoc(ctx context.Context, resourceID string) (*NodePoolDocument, error)
+	// ListClusterDocs retrieves HCPOpenShiftClusterDocuments from the database for the given subscriptionID. If
+	// resourceGroupName or location is not empty, only documents for clusters in that resource group or location
+	// are returned. Both are compared case-insensitively.
+	ListClusterDocs(ctx context.Context, subscriptionID, resourceGroupName, location string) ([]*HCPOpenShiftClusterDocument, error)
+	// ListClusterDocsPage is like ListClusterDocs but retrieves at most pageSize documents, starting from an opaque
+	// continuation returned by a previous call or from the beginning if continuation is empty. It also returns the
+	// continuation for the next page, which is empty on the last page.
+	ListClusterDocsPage(ctx context.Context, subscriptionID, resourceGroupName, location string, pageSize int, continuation string) ([]*HCPOpenShiftClusterDocument, string, error)
+
+	// GetNodePoolDoc retrieves a NodePoolDocument from the database given its resourceID and containing
+	// subscriptionID. ErrNotFound is returned if an associated NodePoolDocument cannot be found.
+	GetNodePoolDoc(ctx context.Context, resourceID string, subscriptionID string) (*NodePoolDocument, error)
+	// SetNodePoolDoc conditionally writes a NodePoolDocument to the database with the same semantics as
+	// SetClusterDoc.
 	SetNodePoolDoc(ctx context.Context, doc *NodePoolDocument) error
-	DeleteNodePoolDoc(ctx context.Context, resourceID string) error
+	// DeleteNodePoolDoc deletes a NodePoolDocument from the database given the resourceID and containing
+	// subscriptionID of a Microsoft.RedHatOpenShift/HcpOpenShiftClusters/NodePools resource. ErrNotFound is
+	// returned if the NodePoolDocument does not exist.
+	DeleteNodePoolDoc(ctx context.Context, resourceID string, subscriptionID string) error
+	// ListNodePoolDocs retrieves the NodePoolDocuments for all node pools of the cluster with the given
+	// clusterResourceID and containing subscriptionID. The clusterResourceID is compared case-insensitively.
+	ListNodePoolDocs(ctx context.Context, clusterResourceID string, subscriptionID string) ([]*NodePoolDocument, error)
 
 	// GetSubscriptionDoc retrieves a SubscriptionDocument from the database given the subscriptionID.
 	// ErrNotFound is returned if an associated SubscriptionDocument cannot be found.
 	GetSubscriptionDoc(ctx context.Context, subscriptionID string) (*SubscriptionDocument, error)
 	SetSubscriptionDoc(ctx context.Context, doc *SubscriptionDocument) error
+
+	// CreateOperationDoc writes a new OperationDocument to the database. A *ConflictError is returned if a document
+	// with the same ID already exists.
+	CreateOperationDoc(ctx context.Context, doc *OperationDocument) error
+	// GetOperationDoc retrieves an OperationDocument from the database given the operationID and containing
+	// subscriptionID. ErrNotFound is returned if an associated OperationDocument cannot be found.
+	GetOperationDoc(ctx context.Context, operationID string, subscriptionID string) (*OperationDocument, error)
+	// UpdateOperationStatus sets the status and error of an OperationDocument given the operationID and containing
+	// subscriptionID. ErrNotFound is returned if an associated OperationDocument cannot be found. A *ConflictError
+	// is returned if the document changes between reading and writing it.
+	UpdateOperationStatus(ctx context.Context, operationID string, subscriptionID string, status arm.ProvisioningState, operationError *arm.CloudErrorBody) (*OperationDocument, error)
+	// ListOperationDocs retrieves all OperationDocuments from the database for the resource with the given
+	// resourceID and containing subscriptionID.
+	ListOperationDocs(ctx context.Context, resourceID string, subscriptionID string) ([]*OperationDocument, error)
+	// ListActiveOperationDocs retrieves all OperationDocuments from the database, across all subscriptions, whose
+	// status is not terminal.
+	ListActiveOperationDocs(ctx context.Context) ([]*OperationDocument, error)
+
+	// CreateBillingDoc writes a new BillingDocument to the database. A *ConflictError is returned if a document
+	// with the same ID already exists.
+	CreateBillingDoc(ctx context.Context, doc *BillingDocument) error
+	// GetBillingDoc retrieves the BillingDocument without a deletion time for the resource with the given
+	// resourceID and containing subscriptionID. ErrNotFound is returned if there is no such BillingDocument.
+	GetBillingDoc(ctx context.Context, resourceID string, subscriptionID string) (*BillingDocument, error)
+	// MarkBillingDocDeleted sets the deletion time of the BillingDocument returned by GetBillingDoc for the
+	// same resourceID and subscriptionID. ErrNotFound is returned if there is no such BillingDocument, and a
+	// *ConflictError if the BillingDocument changed after it was read.
+	MarkBillingDocDeleted(ctx context.Context, resourceID string, subscriptionID string, deletionTime time.Time) error
+
+	// AcquireLease attempts to acquire or renew the named lease for holder. The lease lapses after duration unless
+	// renewed. The return value is true if holder now holds the lease, or false if another holder does.
+	AcquireLease(ctx context.Context, name string, holder string, duration time.Duration) (bool, error)
+	// ReleaseLease gives up the named lease if it is held by holder.
+	ReleaseLease(ctx context.Context, name string, holder string) error
 }
 
 var _ DBClient = &CosmosDBClient{}
@@ -70,6 +149,14 @@ func NewCosmosDBConfig(dbName, dbURL string) *CosmosDBConfig {
 	return c
 }
 
+// newCosmosClientOptions returns the client options a CosmosDBClient
+// depends on, whatever credential it uses.
+func newCosmosClientOptions() *azcosmos.ClientOptions {
+	options := &azcosmos.ClientOptions{}
+	options.PerCallPolicies = append(options.PerCallPolicies, &crossPartitionQueryPolicy{})
+	return options
+}
+
 // NewCosmosDBClient instantiates a Cosmos DatabaseClient targeting Frontends async DB
 func NewCosmosDBClient(config *CosmosDBConfig) (DBClient, error) {
 	cred, err := azidentity.NewDefaultAzureCredential(config.ClientOptions)
@@ -81,7 +168,7 @@ func NewCosmosDBClient(config *CosmosDBConfig) (DBClient, error) {
 		config: config,
 	}
 
-	client, err := azcosmos.NewClient(d.config.DBUrl, cred, nil)
+	client, err := azcosmos.NewClient(d.config.DBUrl, cred, newCosmosClientOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -142,6 +229,10 @@ func (d *CosmosDBClient) GetClusterDoc(ctx context.Context, resourceID string, s
 
 // SetClusterDoc creates/updates a cluster document in the async DB during cluster creation/patching
 func (d *CosmosDBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error {
+	if doc.ETag == "" {
+		doc.ID = documentID(doc.Key)
+	}
+
 	data, err := json.Marshal(doc)
 	if err != nil {
 		return err
@@ -152,11 +243,15 @@ func (d *CosmosDBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClu
 		return err
 	}
 
-	_, err = container.UpsertItem(ctx, azcosmos.NewPartitionKeyString(doc.PartitionKey), data, nil)
+	etag, err := conditionalWrite(ctx, container, doc.PartitionKey, doc.ID, doc.ETag, data)
 	if err != nil {
+		if isConflict(err) {
+			return &ConflictError{Key: doc.Key}
+		}
 		return err
 	}
 
+	doc.ETag = etag
 	return nil
 }
 
@@ -165,7 +260,7 @@ func (d *CosmosDBClient) DeleteClusterDoc(ctx context.Context, resourceID string
 	doc, err := d.GetClusterDoc(ctx, resourceID, subscriptionID)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
-			return nil
+			return err
 		}
 		return fmt.Errorf("while attempting to delete the cluster, failed to get cluster document: %w", err)
 	}
@@ -182,17 +277,228 @@ func (d *CosmosDBClient) DeleteClusterDoc(ctx context.Context, resourceID string
 	return nil
 }
 
-func (d *CosmosDBClient) GetNodePoolDoc(ctx context.Context, resourceID string) (*NodePoolDocument, error) {
-	panic("implement me")
+// ListClusterDocs retrieves cluster documents from the async DB for a subscription ID, optionally
+// narrowed to a resource group or location
+func (d *CosmosDBClient) ListClusterDocs(ctx context.Context, subscriptionID, resourceGroupName, location string) ([]*HCPOpenShiftClusterDocument, error) {
+	queryPager, err := d.newClusterDocsPager(subscriptionID, resourceGroupName, location, &azcosmos.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*HCPOpenShiftClusterDocument
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := unmarshalClusterDocs(queryResponse.Items)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, page...)
+	}
+	return docs, nil
+}
+
+// ListClusterDocsPage retrieves one page of cluster documents from the async DB, using the Cosmos
+// continuation token to resume a previous query
+func (d *CosmosDBClient) ListClusterDocsPage(ctx context.Context, subscriptionID, resourceGroupName, location string, pageSize int, continuation string) ([]*HCPOpenShiftClusterDocument, string, error) {
+	opt := &azcosmos.QueryOptions{PageSizeHint: int32(pageSize)}
+	if continuation != "" {
+		opt.ContinuationToken = &continuation
+	}
+
+	queryPager, err := d.newClusterDocsPager(subscriptionID, resourceGroupName, location, opt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	queryResponse, err := queryPager.NextPage(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	docs, err := unmarshalClusterDocs(queryResponse.Items)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if queryResponse.ContinuationToken != nil {
+		next = *queryResponse.ContinuationToken
+	}
+	return docs, next, nil
+}
+
+// newClusterDocsPager returns a pager over the cluster documents in a subscription, optionally narrowed to a
+// resource group or location
+func (d *CosmosDBClient) newClusterDocsPager(subscriptionID, resourceGroupName, location string, opt *azcosmos.QueryOptions) (*runtime.Pager[azcosmos.QueryItemsResponse], error) {
+	container, err := d.client.NewContainer(d.config.DBName, clustersContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE true"
+	if resourceGroupName != "" {
+		query += " AND STARTSWITH(c.key, @prefix)"
+		opt.QueryParameters = append(opt.QueryParameters, azcosmos.QueryParameter{
+			Name: "@prefix", Value: resourceGroupKeyPrefix(subscriptionID, resourceGroupName)})
+	}
+	if location != "" {
+		query += " AND c.location = @location"
+		opt.QueryParameters = append(opt.QueryParameters, azcosmos.QueryParameter{
+			Name: "@location", Value: strings.ToLower(location)})
+	}
+
+	pk := azcosmos.NewPartitionKeyString(subscriptionID)
+	return container.NewQueryItemsPager(query, pk, opt), nil
+}
+
+func unmarshalClusterDocs(items [][]byte) ([]*HCPOpenShiftClusterDocument, error) {
+	docs := make([]*HCPOpenShiftClusterDocument, 0, len(items))
+	for _, item := range items {
+		var doc *HCPOpenShiftClusterDocument
+		err := json.Unmarshal(item, &doc)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// resourceGroupKeyPrefix returns the prefix shared by the keys of all
+// documents for resources in a resource group. Keys are lowercased
+// resource IDs.
+func resourceGroupKeyPrefix(subscriptionID, resourceGroupName string) string {
+	return strings.ToLower("/subscriptions/" + subscriptionID + "/resourceGroups/" + resourceGroupName + "/")
+}
+
+// GetNodePoolDoc retrieves a node pool document from async DB using resource ID
+func (d *CosmosDBClient) GetNodePoolDoc(ctx context.Context, resourceID string, subscriptionID string) (*NodePoolDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, nodePoolsContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.key = @key"
+	opt := azcosmos.QueryOptions{
+		PageSizeHint:    1,
+		QueryParameters: []azcosmos.QueryParameter{{Name: "@key", Value: resourceID}},
+	}
+
+	pk := azcosmos.NewPartitionKeyString(subscriptionID)
+	queryPager := container.NewQueryItemsPager(query, pk, &opt)
+
+	var doc *NodePoolDocument
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range queryResponse.Items {
+			err = json.Unmarshal(item, &doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if doc != nil {
+		return doc, nil
+	}
+	return nil, ErrNotFound
 }
 
+// SetNodePoolDoc creates/updates a node pool document in the async DB during node pool creation/patching
 func (d *CosmosDBClient) SetNodePoolDoc(ctx context.Context, doc *NodePoolDocument) error {
-	panic("implement me")
+	if doc.ETag == "" {
+		doc.ID = documentID(doc.Key)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	container, err := d.client.NewContainer(d.config.DBName, nodePoolsContainer)
+	if err != nil {
+		return err
+	}
+
+	etag, err := conditionalWrite(ctx, container, doc.PartitionKey, doc.ID, doc.ETag, data)
+	if err != nil {
+		if isConflict(err) {
+			return &ConflictError{Key: doc.Key}
+		}
+		return err
+	}
+
+	doc.ETag = etag
+	return nil
+}
+
+// DeleteNodePoolDoc removes a node pool document from the async DB using resource ID
+func (d *CosmosDBClient) DeleteNodePoolDoc(ctx context.Context, resourceID string, subscriptionID string) error {
+	doc, err := d.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("while attempting to delete the node pool, failed to get node pool document: %w", err)
+	}
+
+	container, err := d.client.NewContainer(d.config.DBName, nodePoolsContainer)
+	if err != nil {
+		return err
+	}
+
+	_, err = container.DeleteItem(ctx, azcosmos.NewPartitionKeyString(subscriptionID), doc.ID, nil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListNodePoolDocs retrieves the node pool documents of a cluster from the async DB using the cluster resource ID
+func (d *CosmosDBClient) ListNodePoolDocs(ctx context.Context, clusterResourceID string, subscriptionID string) ([]*NodePoolDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, nodePoolsContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE STARTSWITH(c.key, @prefix)"
+	opt := azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{{Name: "@prefix", Value: clusterKeyPrefix(clusterResourceID)}},
+	}
+
+	pk := azcosmos.NewPartitionKeyString(subscriptionID)
+	queryPager := container.NewQueryItemsPager(query, pk, &opt)
+
+	var docs []*NodePoolDocument
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range queryResponse.Items {
+			var doc *NodePoolDocument
+			err = json.Unmarshal(item, &doc)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
 }
 
-// DeleteNodePoolDoc removes a NodePool document from the DB using resource ID
-func (d *CosmosDBClient) DeleteNodePoolDoc(ctx context.Context, resourceID string) error {
-	panic("implement me")
+// clusterKeyPrefix returns the prefix shared by the keys of all documents
+// for child resources of a cluster, such as its node pools.
+func clusterKeyPrefix(clusterResourceID string) string {
+	return strings.ToLower(clusterResourceID) + "/"
 }
 
 // GetSubscriptionDoc retreives a subscription document from async DB using the subscription ID
@@ -249,3 +555,372 @@ func (d *CosmosDBClient) SetSubscriptionDoc(ctx context.Context, doc *Subscripti
 	}
 	return nil
 }
+
+// CreateOperationDoc writes an asynchronous operation document to the async DB
+func (d *CosmosDBClient) CreateOperationDoc(ctx context.Context, doc *OperationDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	container, err := d.client.NewContainer(d.config.DBName, asyncContainer)
+	if err != nil {
+		return err
+	}
+
+	_, err = container.CreateItem(ctx, azcosmos.NewPartitionKeyString(doc.PartitionKey), data, nil)
+	if err != nil {
+		if isResponseError(err, http.StatusConflict) {
+			return &ConflictError{Key: doc.ID}
+		}
+		return err
+	}
+	return nil
+}
+
+// GetOperationDoc retrieves an asynchronous operation document from the async DB using the operation ID
+func (d *CosmosDBClient) GetOperationDoc(ctx context.Context, operationID string, subscriptionID string) (*OperationDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, asyncContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := container.ReadItem(ctx, azcosmos.NewPartitionKeyString(subscriptionID), operationID, nil)
+	if err != nil {
+		if isResponseError(err, http.StatusNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var doc *OperationDocument
+	err = json.Unmarshal(response.Value, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// UpdateOperationStatus updates the status of an asynchronous operation document in the async DB
+func (d *CosmosDBClient) UpdateOperationStatus(ctx context.Context, operationID string, subscriptionID string, status arm.ProvisioningState, operationError *arm.CloudErrorBody) (*OperationDocument, error) {
+	doc, err := d.GetOperationDoc(ctx, operationID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Status != status {
+		doc.LastTransitionTime = time.Now().UTC()
+	}
+	doc.Status = status
+	doc.Error = operationError
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := d.client.NewContainer(d.config.DBName, asyncContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guard against concurrent status updates clobbering each other.
+	opt := azcosmos.ItemOptions{IfMatchEtag: (*azcore.ETag)(&doc.ETag)}
+	response, err := container.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(subscriptionID), operationID, data, &opt)
+	if err != nil {
+		if isConflict(err) {
+			return nil, &ConflictError{Key: operationID}
+		}
+		return nil, err
+	}
+
+	doc.ETag = string(response.ETag)
+	return doc, nil
+}
+
+// ListOperationDocs retrieves all asynchronous operation documents from the async DB for a resource ID
+func (d *CosmosDBClient) ListOperationDocs(ctx context.Context, resourceID string, subscriptionID string) ([]*OperationDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, asyncContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.externalId = @externalId"
+	opt := azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{{Name: "@externalId", Value: resourceID}},
+	}
+
+	pk := azcosmos.NewPartitionKeyString(subscriptionID)
+	queryPager := container.NewQueryItemsPager(query, pk, &opt)
+
+	var docs []*OperationDocument
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range queryResponse.Items {
+			var doc *OperationDocument
+			err = json.Unmarshal(item, &doc)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// ListActiveOperationDocs retrieves all non-terminal asynchronous operation documents from the async DB
+func (d *CosmosDBClient) ListActiveOperationDocs(ctx context.Context) ([]*OperationDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, asyncContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE NOT (c.status IN (@succeeded, @failed, @canceled))"
+	opt := azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@succeeded", Value: arm.ProvisioningStateSucceeded},
+			{Name: "@failed", Value: arm.ProvisioningStateFailed},
+			{Name: "@canceled", Value: arm.ProvisioningStateCanceled},
+		},
+	}
+
+	// The partition key is ignored for a cross-partition query.
+	queryPager := container.NewQueryItemsPager(query, azcosmos.NullPartitionKey, &opt)
+
+	var docs []*OperationDocument
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(withCrossPartitionQuery(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range queryResponse.Items {
+			var doc *OperationDocument
+			err = json.Unmarshal(item, &doc)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// CreateBillingDoc writes a billing document to the async DB
+func (d *CosmosDBClient) CreateBillingDoc(ctx context.Context, doc *BillingDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	container, err := d.client.NewContainer(d.config.DBName, billingContainer)
+	if err != nil {
+		return err
+	}
+
+	_, err = container.CreateItem(ctx, azcosmos.NewPartitionKeyString(doc.PartitionKey), data, nil)
+	if err != nil {
+		if isResponseError(err, http.StatusConflict) {
+			return &ConflictError{Key: doc.ID}
+		}
+		return err
+	}
+	return nil
+}
+
+// GetBillingDoc retrieves the billing document for a cluster that has not been deleted from the async DB
+func (d *CosmosDBClient) GetBillingDoc(ctx context.Context, resourceID string, subscriptionID string) (*BillingDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, billingContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.externalId = @externalId AND NOT IS_DEFINED(c.deletionTime)"
+	opt := azcosmos.QueryOptions{
+		PageSizeHint:    1,
+		QueryParameters: []azcosmos.QueryParameter{{Name: "@externalId", Value: resourceID}},
+	}
+
+	pk := azcosmos.NewPartitionKeyString(subscriptionID)
+	queryPager := container.NewQueryItemsPager(query, pk, &opt)
+
+	var doc *BillingDocument
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range queryResponse.Items {
+			err = json.Unmarshal(item, &doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if doc != nil {
+		return doc, nil
+	}
+	return nil, ErrNotFound
+}
+
+// MarkBillingDocDeleted stamps the deletion time on a billing document in the async DB
+func (d *CosmosDBClient) MarkBillingDocDeleted(ctx context.Context, resourceID string, subscriptionID string, deletionTime time.Time) error {
+	doc, err := d.GetBillingDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	deletionTime = deletionTime.UTC()
+	doc.DeletionTime = &deletionTime
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	container, err := d.client.NewContainer(d.config.DBName, billingContainer)
+	if err != nil {
+		return err
+	}
+
+	opt := azcosmos.ItemOptions{IfMatchEtag: (*azcore.ETag)(&doc.ETag)}
+	_, err = container.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(subscriptionID), doc.ID, data, &opt)
+	if err != nil {
+		if isConflict(err) {
+			return &ConflictError{Key: resourceID}
+		}
+		return err
+	}
+	return nil
+}
+
+// AcquireLease acquires or renews a lease document in the async DB. Leases
+// are written conditionally so that only one holder can take over a lapsed
+// lease, and carry a TTL so Cosmos removes a lease abandoned by its holder.
+func (d *CosmosDBClient) AcquireLease(ctx context.Context, name string, holder string, duration time.Duration) (bool, error) {
+	container, err := d.client.NewContainer(d.config.DBName, locksContainer)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+
+	var etag string
+	response, err := container.ReadItem(ctx, azcosmos.NewPartitionKeyString(name), name, nil)
+	if err == nil {
+		var current *LeaseDocument
+		err = json.Unmarshal(response.Value, &current)
+		if err != nil {
+			return false, err
+		}
+		if current.Holder != holder && now.Before(current.Expires) {
+			return false, nil
+		}
+		etag = string(response.ETag)
+	} else if !isResponseError(err, http.StatusNotFound) {
+		return false, err
+	}
+
+	data, err := json.Marshal(&LeaseDocument{
+		ID:           name,
+		PartitionKey: name,
+		Holder:       holder,
+		TTL:          int(duration.Seconds()),
+		Expires:      now.Add(duration),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = conditionalWrite(ctx, container, name, name, etag, data)
+	if err != nil {
+		if isConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease deletes a lease document from the async DB if held by holder
+func (d *CosmosDBClient) ReleaseLease(ctx context.Context, name string, holder string) error {
+	container, err := d.client.NewContainer(d.config.DBName, locksContainer)
+	if err != nil {
+		return err
+	}
+
+	pk := azcosmos.NewPartitionKeyString(name)
+
+	response, err := container.ReadItem(ctx, pk, name, nil)
+	if err != nil {
+		if isResponseError(err, http.StatusNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var current *LeaseDocument
+	err = json.Unmarshal(response.Value, &current)
+	if err != nil {
+		return err
+	}
+	if current.Holder != holder {
+		return nil
+	}
+
+	opt := azcosmos.ItemOptions{IfMatchEtag: &response.ETag}
+	_, err = container.DeleteItem(ctx, pk, name, &opt)
+	if err != nil && !isConflict(err) {
+		return err
+	}
+	return nil
+}
+
+// documentID returns the item ID for the document of the resource with the
+// given key. Item IDs are unique within a partition, so deriving the ID from
+// the key makes Cosmos reject a second document for the same resource.
+func documentID(key string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(key)))
+	return hex.EncodeToString(sum[:])
+}
+
+// conditionalWrite creates the item if etag is empty, or else replaces the
+// item only if its current ETag matches. It returns the item's new ETag.
+// Creating an item that already exists fails with 409 Conflict, which
+// isConflict recognizes.
+func conditionalWrite(ctx context.Context, container *azcosmos.ContainerClient, partitionKey, itemID, etag string, data []byte) (string, error) {
+	var response azcosmos.ItemResponse
+	var err error
+
+	pk := azcosmos.NewPartitionKeyString(partitionKey)
+	if etag == "" {
+		response, err = container.CreateItem(ctx, pk, data, nil)
+	} else {
+		options := &azcosmos.ItemOptions{IfMatchEtag: (*azcore.ETag)(&etag)}
+		response, err = container.ReplaceItem(ctx, pk, itemID, data, options)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(response.ETag), nil
+}
+
+// isConflict returns true if a conditional write failed because the item
+// was created, modified or deleted concurrently.
+func isConflict(err error) bool {
+	return isResponseError(err, http.StatusConflict) ||
+		isResponseError(err, http.StatusPreconditionFailed) ||
+		isResponseError(err, http.StatusNotFound)
+}
+
+// isResponseError returns true if err is an azcore.ResponseError with the given HTTP status code
+func isResponseError(err error, statusCode int) bool {
+	var responseError *azcore.ResponseError
+	return errors.As(err, &responseError) && responseError.StatusCode == statusCode
+}
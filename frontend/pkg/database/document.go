@@ -1,6 +1,12 @@
 package database
 
-import "github.com/Azure/ARO-HCP/internal/api/arm"
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
 
 // HCPOpenShiftClusterDocument represents an HCP OpenShift cluster document.
 type HCPOpenShiftClusterDocument struct {
@@ -10,6 +16,20 @@ type HCPOpenShiftClusterDocument struct {
 	ClusterID    string          `json:"clusterId,omitempty"`
 	SystemData   *arm.SystemData `json:"systemData,omitempty"` // TODO: Should CS store this?
 
+	// Location is the lowercased Azure region of the cluster
+	Location string `json:"location,omitempty"`
+
+	// Identity is the managed identity of the cluster as given on
+	// creation, since Cluster Service does not return it
+	Identity *arm.ManagedServiceIdentity `json:"identity,omitempty"`
+
+	// Tags are the ARM resource tags, which Cluster Service does not store
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// ProvisioningState is maintained by the backend from the status of
+	// the most recent asynchronous operation on the resource
+	ProvisioningState arm.ProvisioningState `json:"provisioningState,omitempty"`
+
 	// Values provided by Cosmos after doc creation
 	ResourceID  string `json:"_rid,omitempty"`
 	Self        string `json:"_self,omitempty"`
@@ -26,6 +46,13 @@ type NodePoolDocument struct {
 	NodePoolID   string          `json:"nodePoolId,omitempty"`
 	SystemData   *arm.SystemData `json:"systemData,omitempty"` // TODO: Should CS store this?
 
+	// Tags are the ARM resource tags, which Cluster Service does not store
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// ProvisioningState is maintained by the backend from the status of
+	// the most recent asynchronous operation on the resource
+	ProvisioningState arm.ProvisioningState `json:"provisioningState,omitempty"`
+
 	// Values provided by Cosmos after doc creation
 	ResourceID  string `json:"_rid,omitempty"`
 	Self        string `json:"_self,omitempty"`
@@ -47,3 +74,145 @@ type SubscriptionDocument struct {
 	Attachments string `json:"_attachments,omitempty"`
 	Timestamp   int    `json:"_ts,omitempty"`
 }
+
+// BillingDocument records the billable lifetime of a cluster for usage
+// reconciliation. A cluster recreated with the same resource ID gets a
+// new document.
+type BillingDocument struct {
+	ID           string `json:"id,omitempty"`
+	PartitionKey string `json:"partitionKey,omitempty"`
+
+	// ExternalID is the Azure resource ID of the cluster
+	ExternalID string `json:"externalId,omitempty"`
+	// SubscriptionID is the Azure subscription containing the cluster
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	// TenantID is the Azure tenant of the subscription
+	TenantID string `json:"tenantId,omitempty"`
+	// Location is the Azure region of the cluster
+	Location string `json:"location,omitempty"`
+	// CreationTime marks when the cluster became ready for use
+	CreationTime time.Time `json:"creationTime,omitempty"`
+	// DeletionTime marks when the cluster was deleted, if it has been
+	DeletionTime *time.Time `json:"deletionTime,omitempty"`
+
+	// Values provided by Cosmos after doc creation
+	ResourceID  string `json:"_rid,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+}
+
+// NewBillingDocument returns a new BillingDocument for a cluster that
+// became ready for use at creationTime.
+func NewBillingDocument(subscriptionID, tenantID, location, resourceID string, creationTime time.Time) *BillingDocument {
+	return &BillingDocument{
+		ID:             uuid.New().String(),
+		PartitionKey:   subscriptionID,
+		ExternalID:     resourceID,
+		SubscriptionID: subscriptionID,
+		TenantID:       tenantID,
+		Location:       location,
+		CreationTime:   creationTime.UTC(),
+	}
+}
+
+// LeaseDocument grants its holder exclusive access to some shared work,
+// such as the backend's reconciliation loop, until it expires.
+type LeaseDocument struct {
+	ID           string `json:"id,omitempty"`
+	PartitionKey string `json:"partitionKey,omitempty"`
+	// Holder identifies the process holding the lease
+	Holder string `json:"holder,omitempty"`
+	// TTL is the lease duration in seconds, measured from the last write
+	TTL int `json:"ttl,omitempty"`
+	// Expires marks when the lease lapses unless renewed
+	Expires time.Time `json:"expires,omitempty"`
+
+	// Values provided by Cosmos after doc creation
+	ResourceID  string `json:"_rid,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+}
+
+// OperationRequest is the type of resource request that started an
+// asynchronous operation.
+type OperationRequest string
+
+const (
+	OperationRequestCreate OperationRequest = "Create"
+	OperationRequestUpdate OperationRequest = "Update"
+	OperationRequestDelete OperationRequest = "Delete"
+
+	// Subscription state changes are recorded as operations on the
+	// subscription itself so the backend can act on them.
+	OperationRequestWarn      OperationRequest = "Warn"
+	OperationRequestSuspend   OperationRequest = "Suspend"
+	OperationRequestReinstate OperationRequest = "Reinstate"
+)
+
+// OperationDocument tracks an asynchronous operation.
+type OperationDocument struct {
+	ID           string `json:"id,omitempty"`
+	PartitionKey string `json:"partitionKey,omitempty"`
+
+	// Request is the type of request that started the operation
+	Request OperationRequest `json:"request,omitempty"`
+	// ExternalID is the Azure resource ID of the resource being operated on
+	ExternalID string `json:"externalId,omitempty"`
+	// InternalID is the Cluster Service ID of the resource being operated on
+	InternalID string `json:"internalId,omitempty"`
+	// StartTime marks the start of the operation
+	StartTime time.Time `json:"startTime,omitempty"`
+	// LastTransitionTime marks the most recent operation status change
+	LastTransitionTime time.Time `json:"lastTransitionTime,omitempty"`
+	// Status is the current operation status, using the same set of values
+	// as the resource's provisioning state
+	Status arm.ProvisioningState `json:"status,omitempty"`
+	// Error is set when Status is Failed or Canceled
+	Error *arm.CloudErrorBody `json:"error,omitempty"`
+
+	// Values provided by Cosmos after doc creation
+	ResourceID  string `json:"_rid,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+}
+
+// NewOperationDocument returns a new OperationDocument in the Accepted state
+// for a request on the resource with the given Azure and Cluster Service IDs.
+func NewOperationDocument(request OperationRequest, subscriptionID, externalID, internalID string) *OperationDocument {
+	now := time.Now().UTC()
+
+	return &OperationDocument{
+		ID:                 uuid.New().String(),
+		PartitionKey:       subscriptionID,
+		Request:            request,
+		ExternalID:         externalID,
+		InternalID:         internalID,
+		StartTime:          now,
+		LastTransitionTime: now,
+		Status:             arm.ProvisioningStateAccepted,
+	}
+}
+
+// ToStatus converts an OperationDocument to the ARM operation status format.
+func (doc *OperationDocument) ToStatus(operationStatusID string) *arm.OperationStatus {
+	status := &arm.OperationStatus{
+		ID:        operationStatusID,
+		Name:      doc.ID,
+		Status:    doc.Status,
+		StartTime: &doc.StartTime,
+		Error:     doc.Error,
+	}
+
+	if doc.Status.IsTerminal() {
+		status.EndTime = &doc.LastTransitionTime
+		status.PercentComplete = 100
+	}
+
+	return status
+}
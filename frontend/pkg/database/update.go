@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+// MaxConflictRetries bounds how many times UpdateClusterDoc and
+// UpdateNodePoolDoc attempt a write when other writers keep updating
+// the document first.
+const MaxConflictRetries = 3
+
+// UpdateClusterDoc reads the HCPOpenShiftClusterDocument for resourceID,
+// passes it to callback and writes it back if callback returns true. If
+// another writer updates the document first, the document is read again
+// and callback called again, up to MaxConflictRetries times.
+func UpdateClusterDoc(ctx context.Context, dbClient DBClient, resourceID, subscriptionID string, callback func(*HCPOpenShiftClusterDocument) bool) error {
+	var err error
+	for range MaxConflictRetries {
+		var doc *HCPOpenShiftClusterDocument
+		doc, err = dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
+		if err != nil {
+			return err
+		}
+		if !callback(doc) {
+			return nil
+		}
+		err = dbClient.SetClusterDoc(ctx, doc)
+		if !isConflictError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// UpdateNodePoolDoc is like UpdateClusterDoc for node pools.
+func UpdateNodePoolDoc(ctx context.Context, dbClient DBClient, resourceID, subscriptionID string, callback func(*NodePoolDocument) bool) error {
+	var err error
+	for range MaxConflictRetries {
+		var doc *NodePoolDocument
+		doc, err = dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+		if err != nil {
+			return err
+		}
+		if !callback(doc) {
+			return nil
+		}
+		err = dbClient.SetNodePoolDoc(ctx, doc)
+		if !isConflictError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isConflictError(err error) bool {
+	var conflictError *ConflictError
+	return errors.As(err, &conflictError)
+}
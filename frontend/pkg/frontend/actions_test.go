@@ -0,0 +1,138 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestArmResourceAction(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	tests := []struct {
+		name               string
+		action             string
+		clusterDoc         bool
+		expectedStatusCode int
+		expectedBody       map[string]string
+	}{
+		{
+			name:               "Admin credentials",
+			action:             "adminCredentials",
+			clusterDoc:         true,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: map[string]string{
+				"kubeadminUsername": "kubeadmin",
+				"kubeadminPassword": "password",
+			},
+		},
+		{
+			name:               "Kubeconfig",
+			action:             "kubeConfig",
+			clusterDoc:         true,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: map[string]string{
+				"kubeconfig": "apiVersion: v1",
+			},
+		},
+		{
+			name:               "Unknown action",
+			action:             "bogus",
+			clusterDoc:         true,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Missing cluster",
+			action:             "kubeConfig",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	cs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/clusters_mgmt/v1/clusters/cluster-id/credentials" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"kind": "ClusterCredentials",
+			"kubeconfig": "apiVersion: v1",
+			"admin": {"user": "kubeadmin", "password": "password"}
+		}`))
+	}))
+	defer cs.Close()
+
+	conn, err := sdk.NewUnauthenticatedConnectionBuilder().URL(cs.URL).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &Frontend{
+				clusterServiceClient: ocm.NewClusterServiceClient(conn),
+				dbClient:             database.NewCache(),
+				logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+				metrics:              NewPrometheusEmitter(),
+				region:               "eastus",
+			}
+
+			err := f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+				PartitionKey: subscriptionID,
+				Subscription: &arm.Subscription{State: arm.Registered},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.clusterDoc {
+				err = f.dbClient.SetClusterDoc(context.TODO(), &database.HCPOpenShiftClusterDocument{
+					Key:          strings.ToLower(clusterPath),
+					PartitionKey: subscriptionID,
+					ClusterID:    "cluster-id",
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ts := newTestServer(t, f)
+
+			url := ts.URL + clusterPath + "/" + test.action + "?api-version=2024-06-10-preview"
+			rs, err := ts.Client().Post(url, "application/json", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rs.Body.Close()
+
+			if rs.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected status code %d, got %d", test.expectedStatusCode, rs.StatusCode)
+			}
+
+			if test.expectedBody != nil {
+				var body map[string]string
+				if err = json.NewDecoder(rs.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				for key, expected := range test.expectedBody {
+					if body[key] != expected {
+						t.Errorf("expected %s %q, got %q", key, expected, body[key])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// Register API versions for tests that exercise versioned routes,
+// the same as the frontend's main package does.
+import (
+	_ "github.com/Azure/ARO-HCP/internal/api/v20240610preview"
+)
@@ -9,6 +9,14 @@ const (
 	// APIVersionKey is the request parameter name for the API version.
 	APIVersionKey = "api-version"
 
+	// SkipTokenKey is the request parameter name for the opaque token
+	// that continues a paginated list.
+	SkipTokenKey = "$skipToken"
+
+	// TopKey is the request parameter name for the requested page size
+	// of a paginated list.
+	TopKey = "$top"
+
 	// Wildcard path segment names for request multiplexing, must be lowercase as we lowercase the request URL pattern when registering handlers
 	PageSegmentLocation          = "location"
 	PathSegmentSubscriptionID    = "subscriptionid"
@@ -17,4 +25,5 @@ const (
 	PathSegmentDeploymentName    = "deploymentname"
 	PathSegmentActionName        = "actionname"
 	PathSegmentNodepoolName      = "nodepoolname"
+	PathSegmentOperationID       = "operationid"
 )
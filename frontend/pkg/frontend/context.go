@@ -137,6 +137,16 @@ func SubscriptionFromContext(ctx context.Context) (arm.Subscription, error) {
 	return sub, nil
 }
 
+// FeaturesFromContext returns the preview features registered to the
+// subscription in the context, or an empty set if there is none.
+func FeaturesFromContext(ctx context.Context) api.FeatureSet {
+	sub, err := SubscriptionFromContext(ctx)
+	if err != nil {
+		return api.FeatureSet{}
+	}
+	return api.NewFeatureSet(&sub)
+}
+
 func TenantIDFromContext(ctx context.Context) (string, error) {
 	sub, ok := ctx.Value(contextKeySubscription).(arm.Subscription)
 	if !ok {
@@ -11,18 +11,16 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
-	"strconv"
 	"strings"
 	"sync/atomic"
 
 	"github.com/google/uuid"
-	sdk "github.com/openshift-online/ocm-sdk-go"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 
 	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
 	"github.com/Azure/ARO-HCP/internal/api"
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
@@ -36,9 +34,12 @@ const (
 	PatternResourceGroups   = "resourcegroups/{" + PathSegmentResourceGroupName + "}"
 	PatternResourceName     = "{" + PathSegmentResourceName + "}"
 	PatternActionName       = "{" + PathSegmentActionName + "}"
+	PatternOperationsStatus = api.OperationStatusResourceTypeName + "/{" + PathSegmentOperationID + "}"
+	PatternOperationResults = api.OperationResultResourceTypeName + "/{" + PathSegmentOperationID + "}"
 )
 
 type Frontend struct {
+	clusterServiceClient ocm.ClusterServiceClient
 	clusterServiceConfig ClusterServiceConfig
 	logger               *slog.Logger
 	listener             net.Listener
@@ -48,12 +49,10 @@ type Frontend struct {
 	done                 chan struct{}
 	metrics              Emitter
 	region               string
+	providerOperations   []arm.Operation
 }
 
 type ClusterServiceConfig struct {
-	// Conn is an ocm-sdk-go connection to Cluster Service
-	Conn *sdk.Connection
-
 	// ProvisionShardID sets the provision_shard_id property for all cluster requests to Cluster Service, which pins all
 	// cluster requests to Cluster Service to a specific shard during testing
 	ProvisionShardID *string
@@ -74,8 +73,9 @@ func MuxPattern(method string, segments ...string) string {
 	return fmt.Sprintf("%s /%s", method, strings.ToLower(path.Join(segments...)))
 }
 
-func NewFrontend(logger *slog.Logger, listener net.Listener, emitter Emitter, dbClient database.DBClient, region string, csCfg ClusterServiceConfig) *Frontend {
+func NewFrontend(logger *slog.Logger, listener net.Listener, emitter Emitter, dbClient database.DBClient, region string, csClient ocm.ClusterServiceClient, csCfg ClusterServiceConfig) *Frontend {
 	f := &Frontend{
+		clusterServiceClient: csClient,
 		clusterServiceConfig: csCfg,
 		logger:               logger,
 		listener:             listener,
@@ -164,49 +164,50 @@ func (f *Frontend) ArmResourceList(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	var query string
-	subscriptionId := request.PathValue(PathSegmentSubscriptionID)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
 	resourceGroupName := request.PathValue(PathSegmentResourceGroupName)
 	location := request.PathValue(PageSegmentLocation)
 
-	switch {
-	case resourceGroupName != "":
-		query = fmt.Sprintf("azure.resource_group_name='%s'", resourceGroupName)
-	case location != "":
-		query = fmt.Sprintf("region.id='%s'", location)
-	case subscriptionId != "" && location == "" && resourceGroupName == "":
-		query = fmt.Sprintf("azure.subscription_id='%s'", subscriptionId)
+	pageSize, token, cloudError := parseListParameters(request)
+	if cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
 	}
 
-	pageSize := 10
-	pageNumber := 1
-
-	if pageStr := request.URL.Query().Get("page"); pageStr != "" {
-		pageNumber, _ = strconv.Atoi(pageStr)
-	}
-	if sizeStr := request.URL.Query().Get("size"); sizeStr != "" {
-		pageSize, _ = strconv.Atoi(sizeStr)
+	// List from the database so the results are exactly the clusters
+	// ARM knows about, then fill in the details from Cluster Service.
+	docs, continuation, err := f.dbClient.ListClusterDocsPage(ctx, subscriptionID, resourceGroupName, location, pageSize, token.Continuation)
+	if err != nil {
+		f.logger.Error(fmt.Sprintf("failed to list cluster documents for subscription %s: %v", subscriptionID, err))
+		arm.WriteInternalServerError(writer)
+		return
 	}
 
-	// Create the request with initial parameters:
-	clustersRequest := f.clusterServiceConfig.Conn.ClustersMgmt().V1().Clusters().List().Search(query)
-	clustersRequest.Size(pageSize)
-	clustersRequest.Page(pageNumber)
+	clusterIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		clusterIDs = append(clusterIDs, doc.ClusterID)
+	}
 
-	// Send the initial request:
-	clustersListResponse, err := clustersRequest.Send()
+	csClusters, err := f.getCSClusters(ctx, clusterIDs)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		f.writeClusterServiceError(writer, request, err)
 		return
 	}
 
-	systemData := &arm.SystemData{}
-	var hcpCluster *api.HCPOpenShiftCluster
-	var versionedHcpClusters []*api.VersionedHCPOpenShiftCluster
-	clusters := clustersListResponse.Items().Slice()
-	for _, cluster := range clusters {
-		hcpCluster, err = f.ConvertCStoHCPOpenShiftCluster(systemData, cluster)
+	result := api.VersionedHCPOpenShiftClusterList{
+		Value: make([]*api.VersionedHCPOpenShiftCluster, 0, len(docs)),
+	}
+
+	for _, doc := range docs {
+		csCluster, ok := csClusters[doc.ClusterID]
+		if !ok {
+			// The backend removes the document once it notices.
+			f.logger.Warn(fmt.Sprintf("cluster %s for %s not found in clusters-service", doc.ClusterID, doc.Key))
+			continue
+		}
+
+		hcpCluster, err := f.clusterFromDocument(doc, csCluster)
 		if err != nil {
 			f.logger.Error(err.Error())
 			arm.WriteInternalServerError(writer)
@@ -214,19 +215,12 @@ func (f *Frontend) ArmResourceList(writer http.ResponseWriter, request *http.Req
 		}
 
 		versionedResource := versionedInterface.NewHCPOpenShiftCluster(hcpCluster)
-		versionedHcpClusters = append(versionedHcpClusters, &versionedResource)
-	}
-
-	// Check if there are more pages to fetch and set NextLink if applicable:
-	var nextLink string
-	if clustersListResponse.Size() >= pageSize {
-		nextPage := pageNumber + 1
-		nextLink = buildNextLink(request.URL.Path, request.URL.Query(), nextPage, pageSize)
+		result.Value = append(result.Value, &versionedResource)
 	}
 
-	result := api.VersionedHCPOpenShiftClusterList{
-		Value:    versionedHcpClusters,
-		NextLink: &nextLink,
+	if continuation != "" {
+		link := nextLink(request, skipToken{Continuation: continuation})
+		result.NextLink = &link
 	}
 
 	resp, err := json.Marshal(result)
@@ -236,12 +230,12 @@ func (f *Frontend) ArmResourceList(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
 	_, err = writer.Write(resp)
 	if err != nil {
 		f.logger.Error(err.Error())
 	}
-
-	writer.WriteHeader(http.StatusOK)
 }
 
 func (f *Frontend) ArmResourceRead(writer http.ResponseWriter, request *http.Request) {
@@ -272,14 +266,13 @@ func (f *Frontend) ArmResourceRead(writer http.ResponseWriter, request *http.Req
 		}
 	}
 
-	cluster, err := f.GetCSCluster(doc.ClusterID)
+	cluster, err := f.clusterServiceClient.GetCSCluster(ctx, doc.ClusterID)
 	if err != nil {
-		f.logger.Error(fmt.Sprintf("cluster not found in clusters-service: %v", err))
-		writer.WriteHeader(http.StatusNoContent)
+		f.writeClusterServiceError(writer, request, fmt.Errorf("failed to fetch cluster %s from clusters-service: %w", doc.ClusterID, err))
 		return
 	}
 
-	hcpCluster, err := f.ConvertCStoHCPOpenShiftCluster(doc.SystemData, cluster.Body())
+	hcpCluster, err := f.clusterFromDocument(doc, cluster)
 	if err != nil {
 		// Should never happen currently
 		f.logger.Error(err.Error())
@@ -294,6 +287,9 @@ func (f *Frontend) ArmResourceRead(writer http.ResponseWriter, request *http.Req
 		arm.WriteInternalServerError(writer)
 		return
 	}
+	if doc.ETag != "" {
+		writer.Header().Set(arm.HeaderNameETag, doc.ETag)
+	}
 	_, err = writer.Write(resp)
 	if err != nil {
 		f.logger.Error(err.Error())
@@ -337,7 +333,6 @@ func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request
 			updating = false
 			f.logger.Info(fmt.Sprintf("existing document not found for cluster - creating one for %s", resourceID))
 			doc = &database.HCPOpenShiftClusterDocument{
-				ID:           uuid.New().String(),
 				Key:          resourceID,
 				PartitionKey: subscriptionID,
 				SystemData:   systemData,
@@ -349,17 +344,21 @@ func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request
 		}
 	}
 
+	if cloudError := CheckPreconditions(request, updating, doc.ETag); cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
 	var hcpCluster *api.HCPOpenShiftCluster
-	var csResp *cmv1.ClusterGetResponse
 	if doc.ClusterID != "" {
-		csResp, err = f.GetCSCluster(doc.ClusterID)
+		csCluster, err := f.clusterServiceClient.GetCSCluster(ctx, doc.ClusterID)
 		if err != nil {
-			f.logger.Error(fmt.Sprintf("failed to fetch document for %s: %v", resourceID, err))
-			arm.WriteInternalServerError(writer)
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to fetch cluster %s from clusters-service: %w", doc.ClusterID, err))
 			return
 		}
-		if csResp.Body() != nil {
-			hcpCluster, err = f.ConvertCStoHCPOpenShiftCluster(doc.SystemData, csResp.Body())
+		if csCluster != nil {
+			hcpCluster, err = f.clusterFromDocument(doc, csCluster)
 			if err != nil {
 				// Should never happen currently
 				f.logger.Error(err.Error())
@@ -370,6 +369,13 @@ func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request
 	}
 	versionedCurrentCluster := versionedInterface.NewHCPOpenShiftCluster(hcpCluster)
 
+	body, err := BodyFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
 	var versionedRequestCluster api.VersionedHCPOpenShiftCluster
 	switch request.Method {
 	case http.MethodPut:
@@ -384,67 +390,178 @@ func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request
 				originalPath, "Resource not found")
 			return
 		}
+		if patchReplacesTags(body) {
+			hcpCluster.Tags = nil
+		}
 		versionedRequestCluster = versionedInterface.NewHCPOpenShiftCluster(hcpCluster)
 	}
 
-	body, err := BodyFromContext(ctx)
-	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
-		return
-	}
 	if err = json.Unmarshal(body, versionedRequestCluster); err != nil {
 		f.logger.Error(err.Error())
 		arm.WriteCloudError(writer, arm.NewUnmarshalCloudError(err))
 		return
 	}
 
-	if cloudError := versionedRequestCluster.ValidateStatic(versionedCurrentCluster, updating, request.Method); cloudError != nil {
+	if cloudError := versionedRequestCluster.ValidateStatic(versionedCurrentCluster, updating, request.Method, FeaturesFromContext(ctx)); cloudError != nil {
 		f.logger.Error(cloudError.Error())
 		arm.WriteCloudError(writer, cloudError)
 		return
 	}
 
+	currentCluster := hcpCluster
 	hcpCluster = api.NewDefaultHCPOpenShiftCluster()
 	versionedRequestCluster.Normalize(hcpCluster)
 
 	hcpCluster.Name = request.PathValue(PathSegmentResourceName)
-	newCsCluster, err := f.BuildCSCluster(ctx, hcpCluster)
+
+	var csCluster *cmv1.Cluster
+	if doc.ClusterID != "" {
+		// recordUpdate applies the modification to the cluster document.
+		recordUpdate := func(doc *database.HCPOpenShiftClusterDocument) {
+			if doc.SystemData != nil && systemData != nil {
+				doc.SystemData.LastModifiedBy = systemData.LastModifiedBy
+				doc.SystemData.LastModifiedByType = systemData.LastModifiedByType
+				doc.SystemData.LastModifiedAt = systemData.LastModifiedAt
+			}
+			doc.Identity = hcpCluster.Identity
+			doc.Tags = hcpCluster.Tags
+		}
+
+		if request.Method == http.MethodPatch && isTagsOnlyPatch(body) {
+			// Nothing to send to Cluster Service, so the update is
+			// complete once the document is written.
+			recordUpdate(doc)
+			err = f.dbClient.SetClusterDoc(ctx, doc)
+			if err != nil {
+				f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to update document for resource %s: %w", resourceID, err))
+				return
+			}
+			f.logger.Info(fmt.Sprintf("document updated for %s", resourceID))
+
+			applyClusterDocument(hcpCluster, doc)
+
+			resp, err := json.Marshal(versionedInterface.NewHCPOpenShiftCluster(hcpCluster))
+			if err != nil {
+				f.logger.Error(err.Error())
+				arm.WriteInternalServerError(writer)
+				return
+			}
+
+			writer.Header().Set(arm.HeaderNameETag, doc.ETag)
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusOK)
+			_, err = writer.Write(resp)
+			if err != nil {
+				f.logger.Error(err.Error())
+			}
+			return
+		}
+
+		csCluster, err = f.BuildCSCluster(ctx, hcpCluster, currentCluster)
+		if err != nil {
+			f.logger.Error(err.Error())
+			arm.WriteInternalServerError(writer)
+			return
+		}
+
+		csCluster, err = f.clusterServiceClient.UpdateCSCluster(ctx, doc.ClusterID, csCluster)
+		if err != nil {
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to update cluster %s: %w", doc.ClusterID, err))
+			return
+		}
+
+		// Cluster Service accepted the update, so record it even if the
+		// document changed since it was read.
+		err = database.UpdateClusterDoc(ctx, f.dbClient, resourceID, subscriptionID, func(updated *database.HCPOpenShiftClusterDocument) bool {
+			recordUpdate(updated)
+			updated.ProvisioningState = arm.ProvisioningStateAccepted
+			doc = updated
+			return true
+		})
+		if err != nil {
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to update document for resource %s: %w", resourceID, err))
+			return
+		}
+		f.logger.Info(fmt.Sprintf("document updated for %s", resourceID))
+	} else {
+		csCluster, err = f.BuildCSCluster(ctx, hcpCluster, nil)
+		if err != nil {
+			f.logger.Error(err.Error())
+			arm.WriteInternalServerError(writer)
+			return
+		}
+
+		csCluster, err = f.clusterServiceClient.PostCSCluster(ctx, csCluster)
+		if err != nil {
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to create cluster for %s: %w", resourceID, err))
+			return
+		}
+
+		doc.ClusterID = csCluster.ID()
+		doc.Location = strings.ToLower(csCluster.Region().ID())
+		doc.Identity = hcpCluster.Identity
+		doc.Tags = hcpCluster.Tags
+		doc.ProvisioningState = arm.ProvisioningStateAccepted
+		err = f.dbClient.SetClusterDoc(ctx, doc)
+		if err != nil {
+			var conflictError *database.ConflictError
+			if errors.As(err, &conflictError) {
+				// A concurrent request created the resource first,
+				// so nothing refers to the cluster just created.
+				if deleteErr := f.clusterServiceClient.DeleteCSCluster(ctx, doc.ClusterID); deleteErr != nil {
+					f.logger.Error(fmt.Sprintf("failed to delete unreferenced cluster %s: %v", doc.ClusterID, deleteErr))
+				}
+			}
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to create document for resource %s: %w", resourceID, err))
+			return
+		}
+		f.logger.Info(fmt.Sprintf("document created for %s", resourceID))
+	}
+
+	hcpCluster, err = f.clusterFromDocument(doc, csCluster)
 	if err != nil {
+		// Should never happen currently
 		f.logger.Error(err.Error())
 		arm.WriteInternalServerError(writer)
 		return
 	}
 
-	req, err := f.PostCSCluster(newCsCluster)
+	operationRequest := database.OperationRequestCreate
+	if updating {
+		operationRequest = database.OperationRequestUpdate
+	}
+	operationDoc := database.NewOperationDocument(operationRequest, subscriptionID, resourceID, doc.ClusterID)
+	err = f.dbClient.CreateOperationDoc(ctx, operationDoc)
 	if err != nil {
-		f.logger.Error(err.Error())
+		f.logger.Error(fmt.Sprintf("failed to create operation document for resource %s: %v", resourceID, err))
 		arm.WriteInternalServerError(writer)
 		return
 	}
-	doc.ClusterID = req.Body().ID()
-	err = f.dbClient.SetClusterDoc(ctx, doc)
-	if err != nil {
-		f.logger.Error(fmt.Sprintf("failed to create document for resource %s: %v", resourceID, err))
-	}
-	f.logger.Info(fmt.Sprintf("document created for %s", resourceID))
 
-	resp, err := json.Marshal(versionedRequestCluster)
+	resp, err := json.Marshal(versionedInterface.NewHCPOpenShiftCluster(hcpCluster))
 	if err != nil {
 		f.logger.Error(err.Error())
 		arm.WriteInternalServerError(writer)
 		return
 	}
-	_, err = writer.Write(resp)
-	if err != nil {
-		f.logger.Error(err.Error())
+
+	f.AddAsyncOperationHeaders(writer, request, operationDoc)
+	if doc.ETag != "" {
+		writer.Header().Set(arm.HeaderNameETag, doc.ETag)
 	}
 
-	switch request.Method {
-	case http.MethodPut:
-		writer.WriteHeader(http.StatusCreated)
-	case http.MethodPatch:
+	switch {
+	case request.Method == http.MethodPatch:
 		writer.WriteHeader(http.StatusAccepted)
+	case updating:
+		writer.WriteHeader(http.StatusOK)
+	default:
+		writer.WriteHeader(http.StatusCreated)
+	}
+
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
 	}
 }
 
@@ -468,6 +585,11 @@ func (f *Frontend) ArmResourceDelete(writer http.ResponseWriter, request *http.R
 	doc, err = f.dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
 	if err != nil {
 		if errors.Is(err, database.ErrNotFound) {
+			if cloudError := CheckPreconditions(request, false, ""); cloudError != nil {
+				f.logger.Error(cloudError.Error())
+				arm.WriteCloudError(writer, cloudError)
+				return
+			}
 			f.logger.Info(fmt.Sprintf("cluster document cannot be deleted -- document not found for %s", resourceID))
 			writer.WriteHeader(http.StatusNoContent)
 			return
@@ -478,29 +600,55 @@ func (f *Frontend) ArmResourceDelete(writer http.ResponseWriter, request *http.R
 		}
 	}
 
+	if cloudError := CheckPreconditions(request, true, doc.ETag); cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
+	operationDoc := database.NewOperationDocument(database.OperationRequestDelete, subscriptionID, resourceID, doc.ClusterID)
+
 	if doc.ClusterID != "" {
-		_, err = f.DeleteCSCluster(doc.ClusterID)
-		if err != nil {
-			f.logger.Error(fmt.Sprintf("failed to delete cluster %s: %v", doc.ClusterID, err))
-			arm.WriteInternalServerError(writer)
+		// A cluster already gone from Cluster Service is left for the
+		// backend to clean up like any other deleted cluster.
+		err = f.clusterServiceClient.DeleteCSCluster(ctx, doc.ClusterID)
+		if err != nil && !ocm.IsNotFound(err) {
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to delete cluster %s: %w", doc.ClusterID, err))
 			return
 		}
-	}
 
-	err = f.dbClient.DeleteClusterDoc(ctx, resourceID, subscriptionID)
-	if err != nil {
-		if errors.Is(err, database.ErrNotFound) {
-			f.logger.Info(fmt.Sprintf("cluster document cannot be deleted -- document not found for %s", resourceID))
-			writer.WriteHeader(http.StatusNoContent)
+		// Cluster Service accepted the deletion, so record it even if the
+		// document changed since it was read. The backend removes the
+		// document once Cluster Service finishes deleting the cluster.
+		err = database.UpdateClusterDoc(ctx, f.dbClient, resourceID, subscriptionID, func(doc *database.HCPOpenShiftClusterDocument) bool {
+			doc.ProvisioningState = arm.ProvisioningStateDeleting
+			return true
+		})
+		if err != nil {
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to update document for resource %s: %w", resourceID, err))
 			return
-		} else {
+		}
+	} else {
+		// Nothing to delete from Cluster Service.
+		operationDoc.Status = arm.ProvisioningStateSucceeded
+
+		err = f.dbClient.DeleteClusterDoc(ctx, resourceID, subscriptionID)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
 			f.logger.Error(err.Error())
 			arm.WriteInternalServerError(writer)
 			return
 		}
+		f.logger.Info(fmt.Sprintf("document deleted for resource %s", resourceID))
+	}
+
+	err = f.dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		f.logger.Error(fmt.Sprintf("failed to create operation document for resource %s: %v", resourceID, err))
+		arm.WriteInternalServerError(writer)
+		return
 	}
-	f.logger.Info(fmt.Sprintf("document deleted for resource %s", resourceID))
 
+	f.AddAsyncOperationHeaders(writer, request, operationDoc)
 	writer.WriteHeader(http.StatusAccepted)
 }
 
@@ -516,7 +664,86 @@ func (f *Frontend) ArmResourceAction(writer http.ResponseWriter, request *http.R
 
 	f.logger.Info(fmt.Sprintf("%s: ArmResourceAction", versionedInterface))
 
-	writer.WriteHeader(http.StatusOK)
+	// URL path is already lowercased by middleware.
+	actionName := request.PathValue(PathSegmentActionName)
+	originalPath, _ := OriginalPathFromContext(ctx)
+	clusterResourceID := path.Dir(originalPath)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	var actionFunc clusterActionFunc
+	for name, fn := range clusterActions {
+		if strings.EqualFold(name, actionName) {
+			actionFunc = fn
+			break
+		}
+	}
+	if actionFunc == nil {
+		f.logger.Error(fmt.Sprintf("unsupported action %s on %s", actionName, clusterResourceID))
+		arm.WriteError(
+			writer, http.StatusNotFound,
+			arm.CloudErrorCodeNotFound, originalPath,
+			"The action '%s' is not supported for resource type '%s'.",
+			path.Base(originalPath), api.ResourceType)
+		return
+	}
+
+	doc := f.getParentClusterDoc(writer, ctx, clusterResourceID, subscriptionID)
+	if doc == nil {
+		return
+	}
+
+	versionedResponse, err := actionFunc(f, ctx, doc, versionedInterface)
+	if err != nil {
+		f.writeClusterServiceError(writer, request, fmt.Errorf("action %s failed for %s: %w", actionName, clusterResourceID, err))
+		return
+	}
+
+	resp, err := json.Marshal(versionedResponse)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
+
+// clusterActionFunc performs a POST action on a cluster and returns a
+// versioned response body.
+type clusterActionFunc func(*Frontend, context.Context, *database.HCPOpenShiftClusterDocument, api.Version) (any, error)
+
+// clusterActions maps the supported cluster action names to their
+// implementations. The provider operations list is derived from it.
+var clusterActions = map[string]clusterActionFunc{
+	api.AdminCredentialsActionName: (*Frontend).actionAdminCredentials,
+	api.KubeconfigActionName:       (*Frontend).actionKubeconfig,
+}
+
+// actionAdminCredentials fetches the kubeadmin credentials for a cluster
+// from Cluster Service.
+func (f *Frontend) actionAdminCredentials(ctx context.Context, doc *database.HCPOpenShiftClusterDocument, versionedInterface api.Version) (any, error) {
+	csCredentials, err := f.clusterServiceClient.GetCSClusterAdminCredentials(ctx, doc.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	return versionedInterface.NewHCPOpenShiftClusterAdminCredentials(&api.HCPOpenShiftClusterAdminCredentials{
+		KubeadminUsername: csCredentials.User(),
+		KubeadminPassword: csCredentials.Password(),
+	}), nil
+}
+
+// actionKubeconfig fetches the admin kubeconfig for a cluster from
+// Cluster Service.
+func (f *Frontend) actionKubeconfig(ctx context.Context, doc *database.HCPOpenShiftClusterDocument, versionedInterface api.Version) (any, error) {
+	csCredentials, err := f.clusterServiceClient.GetCSClusterCredentials(ctx, doc.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	return versionedInterface.NewHCPOpenShiftClusterKubeconfig(&api.HCPOpenShiftClusterKubeconfig{
+		Kubeconfig: csCredentials.Kubeconfig(),
+	}), nil
 }
 
 func (f *Frontend) ArmSubscriptionGet(writer http.ResponseWriter, request *http.Request) {
@@ -570,6 +797,7 @@ func (f *Frontend) ArmSubscriptionPut(writer http.ResponseWriter, request *http.
 
 	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
 
+	var previousState arm.RegistrationState
 	var doc *database.SubscriptionDocument
 	doc, err = f.dbClient.GetSubscriptionDoc(ctx, subscriptionID)
 	if err != nil {
@@ -587,12 +815,14 @@ func (f *Frontend) ArmSubscriptionPut(writer http.ResponseWriter, request *http.
 		}
 	} else {
 		f.logger.Info(fmt.Sprintf("existing document found for subscription - will update document for subscription %s", subscriptionID))
-		doc.Subscription = &subscription
+		previousState = doc.Subscription.State
 
 		messages := getSubscriptionDifferences(doc.Subscription, &subscription)
 		for _, message := range messages {
 			f.logger.Info(message)
 		}
+
+		doc.Subscription = &subscription
 	}
 
 	err = f.dbClient.SetSubscriptionDoc(ctx, doc)
@@ -600,6 +830,26 @@ func (f *Frontend) ArmSubscriptionPut(writer http.ResponseWriter, request *http.
 		f.logger.Error("failed to create document for subscription %s: %v", subscriptionID, err)
 	}
 
+	if subscription.State == arm.Deleted {
+		// Run on every PUT rather than only on the transition so
+		// that ARM retrying a failed request resumes the cleanup.
+		err = f.deleteSubscriptionClusters(ctx, subscriptionID)
+		if err != nil {
+			f.logger.Error(fmt.Sprintf("failed to delete clusters in subscription %s: %v", subscriptionID, err))
+			arm.WriteInternalServerError(writer)
+			return
+		}
+	} else if operationRequest := subscriptionStateRequest(previousState, subscription.State); operationRequest != "" {
+		// URL path is already lowercased by middleware.
+		operationDoc := database.NewOperationDocument(operationRequest, subscriptionID, request.URL.Path, "")
+		err = f.dbClient.CreateOperationDoc(ctx, operationDoc)
+		if err != nil {
+			f.logger.Error(fmt.Sprintf("failed to create operation document for subscription %s: %v", subscriptionID, err))
+			arm.WriteInternalServerError(writer)
+			return
+		}
+	}
+
 	f.metrics.EmitGauge("subscription_lifecycle", 1, map[string]string{
 		"region":         f.region,
 		"subscriptionid": subscriptionID,
@@ -620,6 +870,89 @@ func (f *Frontend) ArmSubscriptionPut(writer http.ResponseWriter, request *http.
 	}
 }
 
+// deleteSubscriptionClusters starts deleting every cluster in a subscription
+// that ARM has deleted. The backend tracks each deletion through its
+// operation document. Clusters with an active Delete operation are skipped.
+func (f *Frontend) deleteSubscriptionClusters(ctx context.Context, subscriptionID string) error {
+	docs, err := f.dbClient.ListClusterDocs(ctx, subscriptionID, "", "")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, doc := range docs {
+		err = f.deleteSubscriptionCluster(ctx, doc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete cluster %s: %w", doc.Key, err))
+		} else {
+			f.logger.Info(fmt.Sprintf("deleting cluster %s in deleted subscription %s", doc.Key, subscriptionID))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *Frontend) deleteSubscriptionCluster(ctx context.Context, doc *database.HCPOpenShiftClusterDocument) error {
+	if doc.ClusterID == "" {
+		// Nothing to delete from Cluster Service.
+		err := f.dbClient.DeleteClusterDoc(ctx, doc.Key, doc.PartitionKey)
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// An active Delete operation means an earlier attempt got as far
+	// as Cluster Service and the backend is tracking the deletion.
+	operationDocs, err := f.dbClient.ListOperationDocs(ctx, doc.Key, doc.PartitionKey)
+	if err != nil {
+		return err
+	}
+	for _, operationDoc := range operationDocs {
+		if operationDoc.Request == database.OperationRequestDelete && !operationDoc.Status.IsTerminal() {
+			return nil
+		}
+	}
+
+	err = f.clusterServiceClient.DeleteCSCluster(ctx, doc.ClusterID)
+	if err != nil && !ocm.IsNotFound(err) {
+		return err
+	}
+
+	err = database.UpdateClusterDoc(ctx, f.dbClient, doc.Key, doc.PartitionKey, func(doc *database.HCPOpenShiftClusterDocument) bool {
+		doc.ProvisioningState = arm.ProvisioningStateDeleting
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create the operation document last so a failure at any step is retried.
+	operationDoc := database.NewOperationDocument(database.OperationRequestDelete, doc.PartitionKey, doc.Key, doc.ClusterID)
+	return f.dbClient.CreateOperationDoc(ctx, operationDoc)
+}
+
+// subscriptionStateRequest returns the operation request that records a
+// subscription state change for the backend, or an empty string if the
+// change needs no action.
+func subscriptionStateRequest(previousState, state arm.RegistrationState) database.OperationRequest {
+	if previousState == state {
+		return ""
+	}
+
+	switch state {
+	case arm.Warned:
+		return database.OperationRequestWarn
+	case arm.Suspended:
+		return database.OperationRequestSuspend
+	case arm.Registered:
+		if previousState == arm.Warned || previousState == arm.Suspended {
+			return database.OperationRequestReinstate
+		}
+	}
+	return ""
+}
+
 func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *http.Request) {
 	var subscriptionID string = request.PathValue(PathSegmentSubscriptionID)
 	var resourceGroup string = request.PathValue(PathSegmentResourceGroupName)
@@ -646,18 +979,33 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 	validate := api.NewValidator()
 	preflightErrors := []arm.CloudErrorBody{}
 
+	// Index every resource in the deployment so references between
+	// them can be checked.
+	resources := make([]deploymentResource, len(deploymentPreflight.Resources))
+	deploymentResources := make(map[string]deploymentResource)
 	for index, raw := range deploymentPreflight.Resources {
-		resource := &arm.DeploymentPreflightResource{}
-		err = json.Unmarshal(raw, resource)
+		resources[index] = deploymentResource{&arm.DeploymentPreflightResource{}, raw}
+		err = json.Unmarshal(raw, resources[index].DeploymentPreflightResource)
 		if err != nil {
 			cloudError = arm.NewUnmarshalCloudError(err)
 			// Preflight is best-effort: a malformed resource is not a validation failure.
 			f.logger.Warn(cloudError.Message)
+			continue
+		}
+		resourceID := strings.ToLower(resources[index].ResourceID(subscriptionID, resourceGroup))
+		deploymentResources[resourceID] = resources[index]
+	}
+
+	for index, resource := range resources {
+		// Resources of other providers are validated by those providers.
+		namespace, _, _ := strings.Cut(resource.Type, "/")
+		if !strings.EqualFold(namespace, api.ProviderNamespace) {
+			continue
 		}
 
 		// This is just "preliminary" validation to ensure all the base resource
 		// fields are present and the API version is valid.
-		resourceErrors := api.ValidateRequest(validate, request.Method, resource)
+		resourceErrors := api.ValidateRequest(validate, request.Method, resource.DeploymentPreflightResource)
 		if len(resourceErrors) > 0 {
 			// Preflight is best-effort: a malformed resource is not a validation failure.
 			f.logger.Warn(
@@ -666,67 +1014,24 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 			continue
 		}
 
-		// API version is already validated by this point.
-		versionedInterface, _ := api.Lookup(resource.APIVersion)
-		versionedCluster := versionedInterface.NewHCPOpenShiftCluster(nil)
-
-		err = json.Unmarshal(raw, versionedCluster)
-		if err != nil {
-			// Preflight is best effort: failure to parse a resource is not a validation failure.
-			f.logger.Warn(fmt.Sprintf("Failed to unmarshal %s resource named '%s': %s", resource.Type, resource.Name, err))
-			continue
-		}
-
-		// Perform static validation as if for a cluster creation request.
-		cloudError := versionedCluster.ValidateStatic(versionedCluster, false, http.MethodPut)
-		if cloudError != nil {
-			var details []arm.CloudErrorBody
-
-			// This avoids double-nesting details when there's multiple errors.
-			//
-			// To illustrate, instead of:
-			//
-			// {
-			//   "code": "MultipleErrorsOccurred"
-			//   "message": "Content validation failed for {{RESOURCE_NAME}}"
-			//   "target": "{{RESOURCE_ID}}"
-			//   "details": [
-			//     {
-			//       "code": "MultipleErrorsOccurred"
-			//       "message": "Content validation failed on multiple fields"
-			//       "details": [
-			//         ...field-specific validation errors...
-			//       ]
-			//     }
-			//   ]
-			// }
-			//
-			// we want:
-			//
-			// {
-			//   "code": "MultipleErrorsOccurred"
-			//   "message": "Content validation failed for {{RESOURCE_NAME}}"
-			//   "target": "{{RESOURCE_ID}}"
-			//   "details": [
-			//     ...field-specific validation errors...
-			//   ]
-			// }
-			//
-			if len(cloudError.CloudErrorBody.Details) > 0 {
-				details = cloudError.CloudErrorBody.Details
-			} else {
-				details = []arm.CloudErrorBody{*cloudError.CloudErrorBody}
+		resourceID := resource.ResourceID(subscriptionID, resourceGroup)
+
+		var resourceError *arm.CloudErrorBody
+		switch {
+		case strings.EqualFold(resource.Type, api.ResourceType):
+			resourceError = f.preflightClusterResource(ctx, resource, resourceID, deploymentResources)
+		case strings.EqualFold(resource.Type, api.NodePoolResourceType):
+			resourceError = f.preflightNodePoolResource(ctx, resource, resourceID, deploymentResources)
+		default:
+			resourceError = &arm.CloudErrorBody{
+				Code:    arm.CloudErrorCodeInvalidResourceType,
+				Message: fmt.Sprintf("The resource type '%s' could not be found in the namespace '%s'", resource.Type, api.ProviderNamespace),
+				Target:  resourceID,
 			}
-			preflightErrors = append(preflightErrors, arm.CloudErrorBody{
-				Code:    cloudError.Code,
-				Message: fmt.Sprintf("Content validation failed for '%s'", resource.Name),
-				Target:  resource.ResourceID(subscriptionID, resourceGroup),
-				Details: details,
-			})
-			continue
 		}
-
-		// FIXME Further preflight steps go here.
+		if resourceError != nil {
+			preflightErrors = append(preflightErrors, *resourceError)
+		}
 	}
 
 	arm.WriteDeploymentPreflightResponse(writer, preflightErrors)
@@ -780,19 +1085,3 @@ func featuresMap(features *[]arm.Feature) map[string]string {
 	}
 	return featureMap
 }
-
-// Function to build the NextLink URL with pagination parameters
-func buildNextLink(basePath string, queryParams url.Values, nextPage, pageSize int) string {
-	// Clone the existing query parameters
-	newParams := make(url.Values)
-	for key, values := range queryParams {
-		newParams[key] = values
-	}
-
-	newParams.Set("page", strconv.Itoa(nextPage))
-	newParams.Set("size", strconv.Itoa(pageSize))
-
-	// Construct the next link URL
-	nextLink := basePath + "?" + newParams.Encode()
-	return nextLink
-}
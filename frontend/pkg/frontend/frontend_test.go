@@ -4,15 +4,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/csfake"
 	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
 	"github.com/Azure/ARO-HCP/internal/api"
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
@@ -43,10 +52,7 @@ func TestReadiness(t *testing.T) {
 				metrics:  NewPrometheusEmitter(),
 			}
 			f.ready.Store(test.ready)
-			ts := httptest.NewServer(f.routes())
-			ts.Config.BaseContext = func(net.Listener) context.Context {
-				return ContextWithLogger(context.Background(), f.logger)
-			}
+			ts := newTestServer(t, f)
 
 			rs, err := ts.Client().Get(ts.URL + "/healthz")
 			if err != nil {
@@ -100,10 +106,7 @@ func TestSubscriptionsGET(t *testing.T) {
 				}
 			}
 
-			ts := httptest.NewServer(f.routes())
-			ts.Config.BaseContext = func(net.Listener) context.Context {
-				return ContextWithLogger(context.Background(), f.logger)
-			}
+			ts := newTestServer(t, f)
 
 			rs, err := ts.Client().Get(ts.URL + "/subscriptions/00000000-0000-0000-0000-000000000000?api-version=2.0")
 			if err != nil {
@@ -187,10 +190,7 @@ func TestSubscriptionsPUT(t *testing.T) {
 				}
 			}
 
-			ts := httptest.NewServer(f.routes())
-			ts.Config.BaseContext = func(net.Listener) context.Context {
-				return ContextWithLogger(context.Background(), f.logger)
-			}
+			ts := newTestServer(t, f)
 
 			req, err := http.NewRequest(http.MethodPut, ts.URL+test.urlPath, bytes.NewReader(body))
 			if err != nil {
@@ -209,3 +209,1113 @@ func TestSubscriptionsPUT(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscriptionStateChange(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const subscriptionPath = "/subscriptions/" + subscriptionID
+	const clusterPath = subscriptionPath + "/resourcegroups/myrg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/"
+
+	putSubscription := func(t *testing.T, ts *httptest.Server, state arm.RegistrationState) {
+		t.Helper()
+
+		body, err := json.Marshal(&arm.Subscription{State: state})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(http.MethodPut, ts.URL+subscriptionPath+"?api-version=2.0", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rs, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Body.Close()
+		if rs.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+		}
+	}
+
+	ctx := context.TODO()
+	csClient := ocm.NewMockClusterServiceClient()
+	f := &Frontend{
+		clusterServiceClient: csClient,
+		dbClient:             database.NewCache(),
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:              NewPrometheusEmitter(),
+	}
+
+	csCluster, err := cmv1.NewCluster().Name("provisioned").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csCluster, err = csClient.PostCSCluster(ctx, csCluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Deleting state without an active Delete operation, as left by a
+	// failed deletion, does not stop the cluster from being deleted.
+	csStaleCluster, err := cmv1.NewCluster().Name("stale").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csStaleCluster, err = csClient.PostCSCluster(ctx, csStaleCluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []*database.HCPOpenShiftClusterDocument{
+		{ID: "1", Key: clusterPath + "provisioned", PartitionKey: subscriptionID, ClusterID: csCluster.ID()},
+		{ID: "2", Key: clusterPath + "unprovisioned", PartitionKey: subscriptionID},
+		{ID: "3", Key: clusterPath + "stale", PartitionKey: subscriptionID, ClusterID: csStaleCluster.ID(), ProvisioningState: arm.ProvisioningStateDeleting},
+	} {
+		if err = f.dbClient.SetClusterDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := newTestServer(t, f)
+
+	putSubscription(t, ts, arm.Registered)
+	putSubscription(t, ts, arm.Suspended)
+
+	operationDocs, err := f.dbClient.ListOperationDocs(ctx, subscriptionPath, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operationDocs) != 1 || operationDocs[0].Request != database.OperationRequestSuspend {
+		t.Errorf("expected one %s operation, got %d operations", database.OperationRequestSuspend, len(operationDocs))
+	}
+
+	// Deleted is sent twice to check that a retry is harmless.
+	putSubscription(t, ts, arm.Deleted)
+	putSubscription(t, ts, arm.Deleted)
+
+	if _, err = csClient.GetCSCluster(ctx, csCluster.ID()); !ocm.IsNotFound(err) {
+		t.Errorf("expected cluster to be deleted from Cluster Service, got %v", err)
+	}
+
+	if _, err = csClient.GetCSCluster(ctx, csStaleCluster.ID()); !ocm.IsNotFound(err) {
+		t.Errorf("expected stale cluster to be deleted from Cluster Service, got %v", err)
+	}
+
+	for _, name := range []string{"provisioned", "stale"} {
+		doc, err := f.dbClient.GetClusterDoc(ctx, clusterPath+name, subscriptionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.ProvisioningState != arm.ProvisioningStateDeleting {
+			t.Errorf("%s: expected provisioning state %q, got %q", name, arm.ProvisioningStateDeleting, doc.ProvisioningState)
+		}
+
+		operationDocs, err = f.dbClient.ListOperationDocs(ctx, clusterPath+name, subscriptionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(operationDocs) != 1 || operationDocs[0].Request != database.OperationRequestDelete {
+			t.Errorf("%s: expected one %s operation, got %d operations", name, database.OperationRequestDelete, len(operationDocs))
+		}
+	}
+
+	_, err = f.dbClient.GetClusterDoc(ctx, clusterPath+"unprovisioned", subscriptionID)
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected document without a cluster ID to be deleted, got %v", err)
+	}
+}
+
+// newTestFrontend returns a Frontend backed by a Cache and the given fake
+// Cluster Service, with a registered subscription.
+func newTestFrontend(t *testing.T, cs *csfake.Server, subscriptionID string) (*Frontend, *httptest.Server) {
+	t.Helper()
+
+	conn, err := cs.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	f := &Frontend{
+		clusterServiceClient: ocm.NewClusterServiceClient(conn),
+		dbClient:             database.NewCache(),
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:              NewPrometheusEmitter(),
+		region:               "eastus",
+	}
+
+	err = f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+		PartitionKey: subscriptionID,
+		Subscription: &arm.Subscription{
+			State:      arm.Registered,
+			Properties: &arm.Properties{TenantId: api.Ptr("11111111-1111-1111-1111-111111111111")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, f)
+
+	return f, ts
+}
+
+// newTestServer starts a test server for the frontend's routes. The base
+// context must be set before the server starts serving.
+func newTestServer(t *testing.T, f *Frontend) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(f.routes())
+	ts.Config.BaseContext = func(net.Listener) context.Context {
+		return ContextWithLogger(context.Background(), f.logger)
+	}
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// doRequest sends a request with an optional JSON body to the test server
+// and decodes a JSON response body into out if non-nil.
+func doRequest(t *testing.T, ts *httptest.Server, method, urlPath, body string, out any) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, ts.URL+urlPath+"?api-version=2024-06-10-preview", reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(arm.HeaderNameARMResourceSystemData, `{"createdBy": "user@example.com", "createdByType": "User"}`)
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	if out != nil {
+		if err = json.NewDecoder(rs.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rs
+}
+
+const testClusterBody = `{
+	"location": "eastus",
+	"properties": {
+		"spec": {
+			"version": {"id": "openshift-v4.16.0", "channelGroup": "stable"},
+			"dns": {"baseDomainPrefix": "prefix"},
+			"network": {"podCidr": "10.128.0.0/14", "serviceCidr": "172.30.0.0/16", "machineCidr": "10.0.0.0/16"},
+			"api": {"visibility": "public"},
+			"platform": {
+				"managedResourceGroup": "myMRG",
+				"subnetId": "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.Network/virtualNetworks/myVNet/subnets/mySubnet",
+				"networkSecurityGroupId": "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.Network/networkSecurityGroups/myNSG"
+			}
+		}
+	}
+}`
+
+// testNodePoolBody is a node pool request body matching testClusterBody.
+const testNodePoolBody = `{
+	"properties": {
+		"spec": {
+			"version": {"id": "openshift-v4.16.0", "channelGroup": "stable"},
+			"platform": {"vmSize": "Standard_D8s_v3"},
+			"replicas": 2
+		}
+	}
+}`
+
+func TestClusterLifecycle(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+	ctx := context.TODO()
+
+	type clusterResponse struct {
+		Name       string `json:"name"`
+		Properties struct {
+			ProvisioningState arm.ProvisioningState `json:"provisioningState"`
+		} `json:"properties"`
+	}
+
+	// Create
+	var created clusterResponse
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, &created)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	if created.Properties.ProvisioningState != arm.ProvisioningStateAccepted {
+		t.Errorf("create: expected provisioning state %q, got %q", arm.ProvisioningStateAccepted, created.Properties.ProvisioningState)
+	}
+
+	doc, err := f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csCluster, err := cs.Cluster(doc.ClusterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csCluster.Azure().TenantID() != "11111111-1111-1111-1111-111111111111" {
+		t.Errorf("create: expected tenant ID to be sent to Cluster Service, got %q", csCluster.Azure().TenantID())
+	}
+
+	// Read
+	var read clusterResponse
+	rs = doRequest(t, ts, http.MethodGet, clusterPath, "", &read)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("read: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if !strings.EqualFold(read.Name, "myCluster") {
+		t.Errorf("read: expected name %q, got %q", "myCluster", read.Name)
+	}
+
+	// Update
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"properties": {"spec": {"disableUserWorkloadMonitoring": true}}}`, nil)
+	if rs.StatusCode != http.StatusAccepted {
+		t.Fatalf("update: expected status code %d, got %d", http.StatusAccepted, rs.StatusCode)
+	}
+	csCluster, err = cs.Cluster(doc.ClusterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !csCluster.DisableUserWorkloadMonitoring() {
+		t.Error("update: expected user workload monitoring to be disabled in Cluster Service")
+	}
+
+	// List
+	var list struct {
+		Value []clusterResponse
+	}
+	rs = doRequest(t, ts, http.MethodGet, "/subscriptions/"+subscriptionID+"/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters", "", &list)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("list: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(list.Value) != 1 || !strings.EqualFold(list.Value[0].Name, "myCluster") {
+		t.Errorf("list: expected only myCluster, got %+v", list.Value)
+	}
+
+	// Delete
+	rs = doRequest(t, ts, http.MethodDelete, clusterPath, "", nil)
+	if rs.StatusCode != http.StatusAccepted {
+		t.Fatalf("delete: expected status code %d, got %d", http.StatusAccepted, rs.StatusCode)
+	}
+	csCluster, err = cs.Cluster(doc.ClusterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csCluster.State() != cmv1.ClusterStateUninstalling {
+		t.Errorf("delete: expected Cluster Service state %q, got %q", cmv1.ClusterStateUninstalling, csCluster.State())
+	}
+	doc, err = f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ProvisioningState != arm.ProvisioningStateDeleting {
+		t.Errorf("delete: expected provisioning state %q, got %q", arm.ProvisioningStateDeleting, doc.ProvisioningState)
+	}
+}
+
+func TestClusterServiceErrors(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+
+	cs.InjectError(http.MethodPost, "/clusters", http.StatusBadRequest, "Version 'openshift-v4.16.0' is not supported")
+	var cloudError arm.CloudError
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, &cloudError)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+	if cloudError.CloudErrorBody == nil {
+		t.Fatal("expected a CloudError response body")
+	}
+	if cloudError.Code != arm.CloudErrorCodeInvalidParameter {
+		t.Errorf("expected error code %q, got %q", arm.CloudErrorCodeInvalidParameter, cloudError.Code)
+	}
+	if cloudError.Target != "properties.spec.version.id" {
+		t.Errorf("expected error target %q, got %q", "properties.spec.version.id", cloudError.Target)
+	}
+
+	cs.InjectError(http.MethodPost, "/clusters", http.StatusConflict, "Cluster 'mycluster' already exists")
+	rs = doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, nil)
+	if rs.StatusCode != http.StatusConflict {
+		t.Errorf("expected status code %d, got %d", http.StatusConflict, rs.StatusCode)
+	}
+
+	cs.ClearErrors()
+	rs = doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, nil)
+	if rs.StatusCode != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+
+	doc, err := f.dbClient.GetClusterDoc(context.TODO(), strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.ProvisioningState = arm.ProvisioningStateSucceeded
+	err = f.dbClient.SetClusterDoc(context.TODO(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An update Cluster Service rejects leaves the cluster as it was.
+	cs.InjectError(http.MethodPatch, "/clusters/"+doc.ClusterID, http.StatusBadRequest, "Cluster cannot be updated")
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"tags": {"team": "a"}, "properties": {"spec": {"disableUserWorkloadMonitoring": true}}}`, nil)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+	doc, err = f.dbClient.GetClusterDoc(context.TODO(), strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ProvisioningState != arm.ProvisioningStateSucceeded {
+		t.Errorf("expected provisioning state %q, got %q", arm.ProvisioningStateSucceeded, doc.ProvisioningState)
+	}
+	if len(doc.Tags) != 0 {
+		t.Errorf("expected no tags, got %v", doc.Tags)
+	}
+
+	// A deletion Cluster Service rejects leaves the cluster as it was.
+	cs.InjectError(http.MethodDelete, "/clusters/"+doc.ClusterID, http.StatusBadRequest, "Cluster cannot be deleted")
+	rs = doRequest(t, ts, http.MethodDelete, clusterPath, "", nil)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+	doc, err = f.dbClient.GetClusterDoc(context.TODO(), strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ProvisioningState != arm.ProvisioningStateSucceeded {
+		t.Errorf("expected provisioning state %q, got %q", arm.ProvisioningStateSucceeded, doc.ProvisioningState)
+	}
+}
+
+func TestConcurrentCreate(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+	const nodePoolPath = clusterPath + "/nodePools/myNodePool"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+	ctx := context.TODO()
+
+	// Another request creates the document while Cluster Service is
+	// creating the cluster for this one.
+	cs.BeforeRequest(http.MethodPost, "/clusters", func() {
+		err := f.dbClient.SetClusterDoc(ctx, &database.HCPOpenShiftClusterDocument{
+			Key:          strings.ToLower(clusterPath),
+			PartitionKey: subscriptionID,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, nil)
+	if rs.StatusCode != http.StatusConflict {
+		t.Fatalf("create cluster: expected status code %d, got %d", http.StatusConflict, rs.StatusCode)
+	}
+	csClusters, _, err := f.clusterServiceClient.ListCSClusters(ctx, "", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(csClusters) != 1 || csClusters[0].State() != cmv1.ClusterStateUninstalling {
+		t.Errorf("create cluster: expected the unreferenced cluster to be deleted from Cluster Service, got %d clusters", len(csClusters))
+	}
+
+	// Start over with a cluster to add a node pool to.
+	err = f.dbClient.DeleteClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.BeforeRequest(http.MethodPost, "/clusters", func() {})
+	rs = doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, nil)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create cluster: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	clusterDoc, err := f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs.BeforeRequest(http.MethodPost, "/clusters/"+clusterDoc.ClusterID+"/node_pools", func() {
+		err := f.dbClient.SetNodePoolDoc(ctx, &database.NodePoolDocument{
+			Key:          strings.ToLower(nodePoolPath),
+			PartitionKey: subscriptionID,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	rs = doRequest(t, ts, http.MethodPut, nodePoolPath, testNodePoolBody, nil)
+	if rs.StatusCode != http.StatusConflict {
+		t.Fatalf("create node pool: expected status code %d, got %d", http.StatusConflict, rs.StatusCode)
+	}
+	csNodePools, err := f.clusterServiceClient.ListCSNodePools(ctx, clusterDoc.ClusterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(csNodePools) != 0 {
+		t.Errorf("create node pool: expected the unreferenced node pool to be deleted from Cluster Service, got %d node pools", len(csNodePools))
+	}
+}
+
+func TestClusterFeatureGate(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+	ctx := context.TODO()
+
+	body := strings.Replace(testClusterBody, `"visibility": "public"`, `"visibility": "private"`, 1)
+
+	var cloudError arm.CloudError
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, body, &cloudError)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+	if cloudError.CloudErrorBody == nil || cloudError.Code != arm.CloudErrorCodeFeatureNotRegistered {
+		t.Errorf("expected error code %q, got %v", arm.CloudErrorCodeFeatureNotRegistered, cloudError.CloudErrorBody)
+	}
+
+	subDoc, err := f.dbClient.GetSubscriptionDoc(ctx, subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subDoc.Subscription.Properties.RegisteredFeatures = &[]arm.Feature{
+		{Name: api.Ptr(api.FeaturePrivateAPI), State: api.Ptr(arm.FeatureStateRegistered)},
+	}
+	err = f.dbClient.SetSubscriptionDoc(ctx, subDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs = doRequest(t, ts, http.MethodPut, clusterPath, body, nil)
+	if rs.StatusCode != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+}
+
+func TestClusterIdentity(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+	const identityID = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.ManagedIdentity/userAssignedIdentities/myIdentity"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+	ctx := context.TODO()
+
+	type clusterResponse struct {
+		Identity *struct {
+			Type string `json:"type"`
+		} `json:"identity"`
+	}
+
+	invalidBody := strings.Replace(testClusterBody, `"location": "eastus",`, `"location": "eastus", "identity": {"type": "UserAssigned", "userAssignedIdentities": {"myIdentity": {}}},`, 1)
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, invalidBody, nil)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid identity: expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+
+	// Cluster Service cannot be given the identities yet.
+	unsupportedBody := strings.Replace(testClusterBody, `"location": "eastus",`, `"location": "eastus", "identity": {"type": "UserAssigned", "userAssignedIdentities": {"`+identityID+`": {}}},`, 1)
+	var cloudError arm.CloudError
+	rs = doRequest(t, ts, http.MethodPut, clusterPath, unsupportedBody, &cloudError)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported identity: expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+	if cloudError.CloudErrorBody == nil || cloudError.Target != "identity.type" {
+		t.Errorf("unsupported identity: expected an error for identity.type, got %+v", cloudError.CloudErrorBody)
+	}
+	clusters, err := f.dbClient.ListClusterDocs(ctx, subscriptionID, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 0 {
+		t.Errorf("unsupported identity: expected no cluster to be created, got %d", len(clusters))
+	}
+
+	body := strings.Replace(testClusterBody, `"location": "eastus",`, `"location": "eastus", "identity": {"type": "None"},`, 1)
+	var created clusterResponse
+	rs = doRequest(t, ts, http.MethodPut, clusterPath, body, &created)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	if created.Identity == nil || created.Identity.Type != "None" {
+		t.Errorf("create: expected identity type None, got %+v", created.Identity)
+	}
+
+	var read clusterResponse
+	rs = doRequest(t, ts, http.MethodGet, clusterPath, "", &read)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("read: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if read.Identity == nil || read.Identity.Type != "None" {
+		t.Errorf("read: expected identity type None, got %+v", read.Identity)
+	}
+
+	// The identity is fixed at creation.
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"identity": {"type": "SystemAssigned"}}`, nil)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("update: expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+}
+
+func TestResourceTags(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+	const nodePoolPath = clusterPath + "/nodePools/myNodePool"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+	ctx := context.TODO()
+
+	type taggedResponse struct {
+		Tags       map[string]string `json:"tags"`
+		SystemData *arm.SystemData   `json:"systemData"`
+		Properties struct {
+			ProvisioningState arm.ProvisioningState `json:"provisioningState"`
+		} `json:"properties"`
+	}
+
+	body := strings.Replace(testClusterBody, `"location": "eastus",`, `"location": "eastus", "tags": {"env": "dev"},`, 1)
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, body, nil)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create cluster: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	clusterDoc, err := f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read taggedResponse
+	rs = doRequest(t, ts, http.MethodGet, clusterPath, "", &read)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("read cluster: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if read.Tags["env"] != "dev" {
+		t.Errorf("read cluster: expected tags from creation, got %v", read.Tags)
+	}
+
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"tags": {"a/b": "value"}}`, nil)
+	if rs.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid tags: expected status code %d, got %d", http.StatusBadRequest, rs.StatusCode)
+	}
+
+	// Tag-only updates must not reach Cluster Service.
+	cs.InjectError(http.MethodPatch, "/clusters/"+clusterDoc.ClusterID, http.StatusInternalServerError, "unexpected update")
+	var updated taggedResponse
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"tags": {"env": "prod", "team": "sre"}}`, &updated)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("update cluster tags: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if updated.Tags["env"] != "prod" || updated.Tags["team"] != "sre" {
+		t.Errorf("update cluster tags: expected updated tags, got %v", updated.Tags)
+	}
+	if rs.Header.Get(arm.HeaderNameAsyncOperation) != "" {
+		t.Error("update cluster tags: expected a synchronous response")
+	}
+
+	var list struct {
+		Value []taggedResponse
+	}
+	rs = doRequest(t, ts, http.MethodGet, "/subscriptions/"+subscriptionID+"/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters", "", &list)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("list clusters: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(list.Value) != 1 || list.Value[0].Tags["team"] != "sre" {
+		t.Errorf("list clusters: expected updated tags, got %+v", list.Value)
+	}
+
+	// PATCH replaces the tags rather than merging them.
+	updated = taggedResponse{}
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"tags": {"env": "prod"}}`, &updated)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("remove cluster tag: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(updated.Tags) != 1 || updated.Tags["env"] != "prod" {
+		t.Errorf("remove cluster tag: expected only the env tag, got %v", updated.Tags)
+	}
+
+	updated = taggedResponse{}
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"tags": {}}`, &updated)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("clear cluster tags: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(updated.Tags) != 0 {
+		t.Errorf("clear cluster tags: expected no tags, got %v", updated.Tags)
+	}
+	cs.ClearErrors()
+
+	// The same applies when the PATCH also changes Cluster Service fields.
+	rs = doRequest(t, ts, http.MethodPatch, clusterPath, `{"tags": {"team": "sre"}, "properties": {"spec": {"disableUserWorkloadMonitoring": true}}}`, nil)
+	if rs.StatusCode != http.StatusAccepted {
+		t.Fatalf("update cluster: expected status code %d, got %d", http.StatusAccepted, rs.StatusCode)
+	}
+	clusterDoc, err = f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusterDoc.Tags) != 1 || clusterDoc.Tags["team"] != "sre" {
+		t.Errorf("update cluster: expected only the team tag, got %v", clusterDoc.Tags)
+	}
+
+	rs = doRequest(t, ts, http.MethodPut, nodePoolPath, `{
+		"tags": {"pool": "workers"},
+		"properties": {
+			"spec": {
+				"version": {"id": "openshift-v4.16.0", "channelGroup": "stable"},
+				"platform": {"vmSize": "Standard_D8s_v3"},
+				"replicas": 2
+			}
+		}
+	}`, nil)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create node pool: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, strings.ToLower(nodePoolPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs.InjectError(http.MethodPatch, "/clusters/"+clusterDoc.ClusterID+"/node_pools/"+nodePoolDoc.NodePoolID, http.StatusInternalServerError, "unexpected update")
+	rs = doRequest(t, ts, http.MethodPatch, nodePoolPath, `{"tags": {"pool": "infra"}}`, nil)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("update node pool tags: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+
+	rs = doRequest(t, ts, http.MethodGet, nodePoolPath, "", &read)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("read node pool: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if read.Tags["pool"] != "infra" {
+		t.Errorf("read node pool: expected updated tags, got %v", read.Tags)
+	}
+
+	// PATCH replaces the tags rather than merging them.
+	var updatedNodePool taggedResponse
+	rs = doRequest(t, ts, http.MethodPatch, nodePoolPath, `{"tags": {"team": "sre"}}`, &updatedNodePool)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("replace node pool tags: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(updatedNodePool.Tags) != 1 || updatedNodePool.Tags["team"] != "sre" {
+		t.Errorf("replace node pool tags: expected only the team tag, got %v", updatedNodePool.Tags)
+	}
+	if updatedNodePool.Properties.ProvisioningState != arm.ProvisioningStateAccepted {
+		t.Errorf("replace node pool tags: expected provisioning state %q, got %q", arm.ProvisioningStateAccepted, updatedNodePool.Properties.ProvisioningState)
+	}
+	if updatedNodePool.SystemData == nil {
+		t.Error("replace node pool tags: expected system data")
+	}
+
+	updatedNodePool = taggedResponse{}
+	rs = doRequest(t, ts, http.MethodPatch, nodePoolPath, `{"tags": {}}`, &updatedNodePool)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("clear node pool tags: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(updatedNodePool.Tags) != 0 {
+		t.Errorf("clear node pool tags: expected no tags, got %v", updatedNodePool.Tags)
+	}
+	cs.ClearErrors()
+
+	rs = doRequest(t, ts, http.MethodPatch, nodePoolPath, `{"tags": {"pool": "infra"}, "properties": {"spec": {"replicas": 3}}}`, nil)
+	if rs.StatusCode != http.StatusAccepted {
+		t.Fatalf("update node pool: expected status code %d, got %d", http.StatusAccepted, rs.StatusCode)
+	}
+	nodePoolDoc, err = f.dbClient.GetNodePoolDoc(ctx, strings.ToLower(nodePoolPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodePoolDoc.Tags) != 1 || nodePoolDoc.Tags["pool"] != "infra" {
+		t.Errorf("update node pool: expected only the pool tag, got %v", nodePoolDoc.Tags)
+	}
+}
+
+func TestNodePoolLifecycle(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+	const nodePoolPath = clusterPath + "/nodePools/myNodePool"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	f, ts := newTestFrontend(t, cs, subscriptionID)
+	ctx := context.TODO()
+
+	rs := doRequest(t, ts, http.MethodPut, clusterPath, testClusterBody, nil)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create cluster: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	clusterDoc, err := f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type nodePoolResponse struct {
+		Name       string `json:"name"`
+		Properties struct {
+			ProvisioningState arm.ProvisioningState `json:"provisioningState"`
+		} `json:"properties"`
+	}
+
+	// Create
+	var created nodePoolResponse
+	rs = doRequest(t, ts, http.MethodPut, nodePoolPath, testNodePoolBody, &created)
+	if rs.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected status code %d, got %d", http.StatusCreated, rs.StatusCode)
+	}
+	if created.Properties.ProvisioningState != arm.ProvisioningStateAccepted {
+		t.Errorf("create: expected provisioning state %q, got %q", arm.ProvisioningStateAccepted, created.Properties.ProvisioningState)
+	}
+
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, strings.ToLower(nodePoolPath), subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cs.NodePool(clusterDoc.ClusterID, nodePoolDoc.NodePoolID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read
+	rs = doRequest(t, ts, http.MethodGet, nodePoolPath, "", nil)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("read: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+
+	// Replace
+	var replaced nodePoolResponse
+	rs = doRequest(t, ts, http.MethodPut, nodePoolPath, testNodePoolBody, &replaced)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("replace: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if !strings.EqualFold(replaced.Name, "myNodePool") {
+		t.Errorf("replace: expected name %q, got %q", "myNodePool", replaced.Name)
+	}
+	if replaced.Properties.ProvisioningState != arm.ProvisioningStateAccepted {
+		t.Errorf("replace: expected provisioning state %q, got %q", arm.ProvisioningStateAccepted, replaced.Properties.ProvisioningState)
+	}
+
+	// Update
+	rs = doRequest(t, ts, http.MethodPatch, nodePoolPath, `{"properties": {"spec": {"replicas": 4}}}`, nil)
+	if rs.StatusCode != http.StatusAccepted {
+		t.Fatalf("update: expected status code %d, got %d", http.StatusAccepted, rs.StatusCode)
+	}
+	csNodePool, err := cs.NodePool(clusterDoc.ClusterID, nodePoolDoc.NodePoolID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csNodePool.Replicas() != 4 {
+		t.Errorf("update: expected 4 replicas in Cluster Service, got %d", csNodePool.Replicas())
+	}
+
+	// List
+	var list struct {
+		Value []struct {
+			Properties struct {
+				ProvisioningState arm.ProvisioningState `json:"provisioningState"`
+			} `json:"properties"`
+		}
+	}
+	rs = doRequest(t, ts, http.MethodGet, clusterPath+"/nodePools", "", &list)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("list: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if len(list.Value) != 1 {
+		t.Fatalf("list: expected 1 node pool, got %d", len(list.Value))
+	}
+	if list.Value[0].Properties.ProvisioningState != arm.ProvisioningStateAccepted {
+		t.Errorf("list: expected provisioning state %q, got %q", arm.ProvisioningStateAccepted, list.Value[0].Properties.ProvisioningState)
+	}
+
+	// Delete
+	rs = doRequest(t, ts, http.MethodDelete, nodePoolPath, "", nil)
+	if rs.StatusCode != http.StatusAccepted {
+		t.Fatalf("delete: expected status code %d, got %d", http.StatusAccepted, rs.StatusCode)
+	}
+	if _, err = cs.NodePool(clusterDoc.ClusterID, nodePoolDoc.NodePoolID); err == nil {
+		t.Error("delete: expected node pool to be removed from Cluster Service")
+	}
+}
+
+func TestArmResourceList(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const providerPath = "/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters"
+
+	tests := []struct {
+		name               string
+		path               string
+		csError            error
+		expectedStatusCode int
+		expectedNames      []string
+	}{
+		{
+			name:               "Clusters in subscription",
+			path:               "/subscriptions/" + subscriptionID + providerPath,
+			expectedStatusCode: http.StatusOK,
+			expectedNames:      []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name:               "Clusters in resource group",
+			path:               "/subscriptions/" + subscriptionID + "/resourceGroups/myRG" + providerPath,
+			expectedStatusCode: http.StatusOK,
+			expectedNames:      []string{"cluster1", "cluster2"},
+		},
+		{
+			name:               "Clusters in location",
+			path:               "/subscriptions/" + subscriptionID + "/locations/eastus" + providerPath,
+			expectedStatusCode: http.StatusOK,
+			expectedNames:      []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name:               "Clusters in other location",
+			path:               "/subscriptions/" + subscriptionID + "/locations/westus" + providerPath,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Cluster Service failure",
+			path:               "/subscriptions/" + subscriptionID + providerPath,
+			csError:            errors.New("connection refused"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			csClient := ocm.NewMockClusterServiceClient()
+			f := &Frontend{
+				clusterServiceClient: csClient,
+				dbClient:             database.NewCache(),
+				logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+				metrics:              NewPrometheusEmitter(),
+			}
+
+			err := f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+				PartitionKey: subscriptionID,
+				Subscription: &arm.Subscription{State: arm.Registered},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clusters := []struct {
+				resourceGroup string
+				name          string
+				document      bool
+			}{
+				{resourceGroup: "myRG", name: "cluster1", document: true},
+				{resourceGroup: "myRG", name: "cluster2", document: true},
+				{resourceGroup: "otherRG", name: "cluster3", document: true},
+				// Cluster Service clusters unknown to ARM are not listed.
+				{resourceGroup: "myRG", name: "orphan", document: false},
+			}
+			for _, c := range clusters {
+				cluster, err := cmv1.NewCluster().
+					Region(cmv1.NewCloudRegion().ID("eastus")).
+					Azure(cmv1.NewAzure().
+						SubscriptionID(subscriptionID).
+						ResourceGroupName(c.resourceGroup).
+						ResourceName(c.name)).
+					Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+				cluster, err = csClient.PostCSCluster(context.TODO(), cluster)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !c.document {
+					continue
+				}
+				err = f.dbClient.SetClusterDoc(context.TODO(), &database.HCPOpenShiftClusterDocument{
+					Key:          strings.ToLower("/subscriptions/" + subscriptionID + "/resourceGroups/" + c.resourceGroup + providerPath + "/" + c.name),
+					PartitionKey: subscriptionID,
+					ClusterID:    cluster.ID(),
+					Location:     "eastus",
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			csClient.Err = test.csError
+
+			ts := newTestServer(t, f)
+
+			var list struct {
+				Value []struct {
+					Name string
+				}
+			}
+			rs := doRequest(t, ts, http.MethodGet, test.path, "", &list)
+			if rs.StatusCode != test.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", test.expectedStatusCode, rs.StatusCode)
+			}
+
+			var names []string
+			for _, cluster := range list.Value {
+				names = append(names, cluster.Name)
+			}
+			if !slices.Equal(names, test.expectedNames) {
+				t.Errorf("expected clusters %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestArmResourceListPagination(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const listPath = "/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters"
+
+	csClient := ocm.NewMockClusterServiceClient()
+	f := &Frontend{
+		clusterServiceClient: csClient,
+		dbClient:             database.NewCache(),
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:              NewPrometheusEmitter(),
+	}
+
+	err := f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+		PartitionKey: subscriptionID,
+		Subscription: &arm.Subscription{State: arm.Registered},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expectedNames []string
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("cluster%d", i)
+		cluster, err := cmv1.NewCluster().
+			Azure(cmv1.NewAzure().
+				SubscriptionID(subscriptionID).
+				ResourceGroupName("myRG").
+				ResourceName(name)).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cluster, err = csClient.PostCSCluster(context.TODO(), cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.dbClient.SetClusterDoc(context.TODO(), &database.HCPOpenShiftClusterDocument{
+			Key:          strings.ToLower("/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/" + name),
+			PartitionKey: subscriptionID,
+			ClusterID:    cluster.ID(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedNames = append(expectedNames, name)
+	}
+
+	ts := newTestServer(t, f)
+
+	get := func(t *testing.T, requestURL string) (*http.Response, map[string]json.RawMessage) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ARM passes the URL it received in the Referer header.
+		req.Header.Set("Referer", requestURL)
+
+		rs, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rs.Body.Close()
+
+		var body map[string]json.RawMessage
+		if err = json.NewDecoder(rs.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return rs, body
+	}
+
+	t.Run("Follow next links", func(t *testing.T) {
+		var names []string
+		requestURL := ts.URL + listPath + "?api-version=2024-06-10-preview&$top=2"
+		for pages := 1; requestURL != ""; pages++ {
+			if pages > len(expectedNames) {
+				t.Fatal("too many pages")
+			}
+
+			rs, body := get(t, requestURL)
+			if rs.StatusCode != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+			}
+
+			var value []struct {
+				Name string
+			}
+			if err := json.Unmarshal(body["value"], &value); err != nil {
+				t.Fatal(err)
+			}
+			if len(value) > 2 {
+				t.Errorf("expected at most 2 clusters per page, got %d", len(value))
+			}
+			for _, cluster := range value {
+				names = append(names, cluster.Name)
+			}
+
+			requestURL = ""
+			if raw, ok := body["nextLink"]; ok {
+				if err := json.Unmarshal(raw, &requestURL); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasPrefix(requestURL, ts.URL+listPath+"?") || !strings.Contains(requestURL, url.QueryEscape(SkipTokenKey)+"=") {
+					t.Fatalf("expected an absolute next link with a skip token, got %q", requestURL)
+				}
+			}
+		}
+
+		if !slices.Equal(names, expectedNames) {
+			t.Errorf("expected clusters %v, got %v", expectedNames, names)
+		}
+	})
+
+	t.Run("Page size capped", func(t *testing.T) {
+		rs, body := get(t, ts.URL+listPath+"?api-version=2024-06-10-preview&$top=1000")
+		if rs.StatusCode != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+		}
+		if _, ok := body["nextLink"]; ok {
+			t.Errorf("expected no next link on the last page, got %s", body["nextLink"])
+		}
+	})
+
+	for _, query := range []string{"$top=0", "$top=ten", SkipTokenKey + "=not-a-token"} {
+		t.Run("Invalid "+query, func(t *testing.T) {
+			rs, body := get(t, ts.URL+listPath+"?api-version=2024-06-10-preview&"+query)
+			if rs.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status code %d, got %d: %s", http.StatusBadRequest, rs.StatusCode, body["error"])
+			}
+		})
+	}
+}
@@ -68,6 +68,7 @@ func (m *Middleware) HandlerFunc(handler func(http.ResponseWriter, *http.Request
 type MiddlewareMux struct {
 	http.ServeMux
 	middleware Middleware
+	patterns   []string
 }
 
 // NewMiddlewareMux allocates and returns a new MiddlewareMux.
@@ -77,6 +78,23 @@ func NewMiddlewareMux(functions ...MiddlewareFunc) *MiddlewareMux {
 	return mux
 }
 
+// Handle registers the handler for the given pattern.
+func (mux *MiddlewareMux) Handle(pattern string, handler http.Handler) {
+	mux.ServeMux.Handle(pattern, handler)
+	mux.patterns = append(mux.patterns, pattern)
+}
+
+// HandleFunc registers the handler function for the given pattern.
+func (mux *MiddlewareMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	mux.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Patterns returns the patterns registered with the mux, in the
+// order they were registered.
+func (mux *MiddlewareMux) Patterns() []string {
+	return mux.patterns
+}
+
 // ServeHTTP dispatches the request to each middleware function, and then to
 // the handler whose pattern most closely matches the request URL.
 func (mux *MiddlewareMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -6,6 +6,7 @@ package frontend
 import (
 	"net/http"
 	"regexp"
+	"strings"
 
 	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/google/uuid"
@@ -40,8 +41,10 @@ func MiddlewareValidateStatic(w http.ResponseWriter, r *http.Request, next http.
 			}
 		}
 
-		// Skip static validation for subscription resources
-		if resourceType.String() != resourceTypeSubscription {
+		// Skip static validation for subscription resources and for
+		// location-scoped resources like asynchronous operation statuses,
+		// whose names are generated by us rather than the user.
+		if resourceType.String() != resourceTypeSubscription && !isLocationScoped(resourceID) {
 			if resourceID.ResourceGroupName != "" {
 				if !rxResourceGroupName.MatchString(resourceID.ResourceGroupName) {
 					arm.WriteError(w, http.StatusBadRequest,
@@ -69,3 +72,9 @@ func MiddlewareValidateStatic(w http.ResponseWriter, r *http.Request, next http.
 
 	next(w, r)
 }
+
+// isLocationScoped returns true if the resource is nested directly under
+// a provider's "locations" resource type.
+func isLocationScoped(resourceID *azcorearm.ResourceID) bool {
+	return resourceID.Parent != nil && strings.EqualFold(resourceID.Parent.ResourceType.Type, "locations")
+}
@@ -62,6 +62,11 @@ func TestMiddlewareValidateStatic(t *testing.T) {
 			path:               "/SUBSCRIPTIONS/00000000-0000-0000-0000-000000000000",
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "Valid request for an operation status resource",
+			path:               "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOperationsStatus/7d9c3c8d-1d5e-4b0e-8f2a-6a4b2c1d0e9f",
+			expectedStatusCode: http.StatusOK,
+		},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,534 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// getParentClusterDoc fetches the document for the cluster a node pool
+// or action request refers to. The cluster resource ID should retain its original
+// case for error reporting. If the cluster does not exist, it writes a 404
+// response and returns nil.
+func (f *Frontend) getParentClusterDoc(writer http.ResponseWriter, ctx context.Context, clusterResourceID, subscriptionID string) *database.HCPOpenShiftClusterDocument {
+	doc, err := f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterResourceID), subscriptionID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			f.logger.Error(fmt.Sprintf("existing document not found for cluster: %s", clusterResourceID))
+			writeResourceNotFoundError(writer, clusterResourceID)
+		} else {
+			f.logger.Error(fmt.Sprintf("failed to fetch document for %s: %v", clusterResourceID, err))
+			arm.WriteInternalServerError(writer)
+		}
+		return nil
+	}
+	return doc
+}
+
+// getNodePool fetches a node pool from Cluster Service and converts it
+// to the internal API representation.
+func (f *Frontend) getNodePool(ctx context.Context, clusterDoc *database.HCPOpenShiftClusterDocument, nodePoolDoc *database.NodePoolDocument) (*api.HCPOpenShiftClusterNodePool, error) {
+	csNodePool, err := f.clusterServiceClient.GetCSNodePool(ctx, clusterDoc.ClusterID, nodePoolDoc.NodePoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch node pool %s from clusters-service: %w", nodePoolDoc.NodePoolID, err)
+	}
+	return f.nodePoolFromDocument(nodePoolDoc, csNodePool)
+}
+
+func (f *Frontend) ArmNodePoolList(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmNodePoolList", versionedInterface))
+
+	// URL path is already lowercased by middleware.
+	clusterResourceID := path.Dir(request.URL.Path)
+	originalPath, _ := OriginalPathFromContext(ctx)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	clusterDoc := f.getParentClusterDoc(writer, ctx, path.Dir(originalPath), subscriptionID)
+	if clusterDoc == nil {
+		return
+	}
+
+	csNodePools, err := f.clusterServiceClient.ListCSNodePools(ctx, clusterDoc.ClusterID)
+	if err != nil {
+		f.writeClusterServiceError(writer, request, fmt.Errorf("failed to list node pools for cluster %s: %w", clusterDoc.ClusterID, err))
+		return
+	}
+
+	result := api.VersionedHCPOpenShiftClusterNodePoolList{
+		Value: make([]*api.VersionedHCPOpenShiftClusterNodePool, 0, len(csNodePools)),
+	}
+
+	for _, csNodePool := range csNodePools {
+		var hcpNodePool *api.HCPOpenShiftClusterNodePool
+
+		nodePoolResourceID := path.Join(clusterResourceID, api.NodePoolResourceTypeName, csNodePool.ID())
+		nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, nodePoolResourceID, subscriptionID)
+		if err == nil {
+			hcpNodePool, err = f.nodePoolFromDocument(nodePoolDoc, csNodePool)
+		} else {
+			hcpNodePool, err = f.ConvertCStoNodepool(clusterResourceID, nil, csNodePool)
+		}
+		if err != nil {
+			f.logger.Error(err.Error())
+			arm.WriteInternalServerError(writer)
+			return
+		}
+
+		versionedResource := versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool)
+		result.Value = append(result.Value, &versionedResource)
+	}
+
+	resp, err := json.Marshal(result)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
+
+func (f *Frontend) ArmNodePoolRead(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmNodePoolRead", versionedInterface))
+
+	// URL path is already lowercased by middleware.
+	resourceID := request.URL.Path
+	originalPath, _ := OriginalPathFromContext(ctx)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	clusterDoc := f.getParentClusterDoc(writer, ctx, path.Dir(path.Dir(originalPath)), subscriptionID)
+	if clusterDoc == nil {
+		return
+	}
+
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			f.logger.Error(fmt.Sprintf("existing document not found for node pool: %s", resourceID))
+			writeResourceNotFoundError(writer, originalPath)
+		} else {
+			f.logger.Error(fmt.Sprintf("failed to fetch document for %s: %v", resourceID, err))
+			arm.WriteInternalServerError(writer)
+		}
+		return
+	}
+
+	hcpNodePool, err := f.getNodePool(ctx, clusterDoc, nodePoolDoc)
+	if err != nil {
+		f.writeClusterServiceError(writer, request, err)
+		return
+	}
+
+	resp, err := json.Marshal(versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool))
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	if nodePoolDoc.ETag != "" {
+		writer.Header().Set(arm.HeaderNameETag, nodePoolDoc.ETag)
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
+
+func (f *Frontend) ArmNodePoolCreateOrUpdate(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	// This handles both PUT and PATCH requests. The only notable
+	// difference is PATCH requests will not create a new node pool.
+
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	systemData, err := SystemDataFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmNodePoolCreateOrUpdate", versionedInterface))
+
+	// URL path is already lowercased by middleware.
+	resourceID := request.URL.Path
+	originalPath, _ := OriginalPathFromContext(ctx)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	clusterDoc := f.getParentClusterDoc(writer, ctx, path.Dir(path.Dir(originalPath)), subscriptionID)
+	if clusterDoc == nil {
+		return
+	}
+
+	var updating bool = true
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			updating = false
+			f.logger.Info(fmt.Sprintf("existing document not found for node pool - creating one for %s", resourceID))
+			nodePoolDoc = &database.NodePoolDocument{
+				Key:          resourceID,
+				PartitionKey: subscriptionID,
+				SystemData:   systemData,
+			}
+		} else {
+			f.logger.Error(fmt.Sprintf("failed to fetch document for %s: %v", resourceID, err))
+			arm.WriteInternalServerError(writer)
+			return
+		}
+	}
+
+	if cloudError := CheckPreconditions(request, updating, nodePoolDoc.ETag); cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
+	var hcpNodePool *api.HCPOpenShiftClusterNodePool
+	if updating {
+		hcpNodePool, err = f.getNodePool(ctx, clusterDoc, nodePoolDoc)
+		if err != nil {
+			f.writeClusterServiceError(writer, request, err)
+			return
+		}
+	}
+	versionedCurrentNodePool := versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool)
+
+	body, err := BodyFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	var versionedRequestNodePool api.VersionedHCPOpenShiftClusterNodePool
+	switch request.Method {
+	case http.MethodPut:
+		versionedRequestNodePool = versionedInterface.NewHCPOpenShiftClusterNodePool(nil)
+	case http.MethodPatch:
+		if !updating {
+			// PATCH request will not create a new node pool.
+			f.logger.Error(fmt.Sprintf("existing document not found for node pool: %s", resourceID))
+			writeResourceNotFoundError(writer, originalPath)
+			return
+		}
+		if patchReplacesTags(body) {
+			hcpNodePool.Tags = nil
+		}
+		versionedRequestNodePool = versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool)
+	}
+
+	if err = json.Unmarshal(body, versionedRequestNodePool); err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteCloudError(writer, arm.NewUnmarshalCloudError(err))
+		return
+	}
+
+	if cloudError := versionedRequestNodePool.ValidateStatic(versionedCurrentNodePool, updating, request.Method); cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
+	hcpNodePool = api.NewDefaultHCPOpenShiftClusterNodepool()
+	versionedRequestNodePool.Normalize(hcpNodePool)
+
+	hcpNodePool.Name = request.PathValue(PathSegmentNodepoolName)
+
+	// recordUpdate applies the modification to the node pool document.
+	recordUpdate := func(doc *database.NodePoolDocument) {
+		if doc.SystemData != nil && systemData != nil {
+			doc.SystemData.LastModifiedBy = systemData.LastModifiedBy
+			doc.SystemData.LastModifiedByType = systemData.LastModifiedByType
+			doc.SystemData.LastModifiedAt = systemData.LastModifiedAt
+		}
+		doc.Tags = hcpNodePool.Tags
+	}
+
+	if request.Method == http.MethodPatch && isTagsOnlyPatch(body) {
+		// Nothing to send to Cluster Service, so the update is
+		// complete once the document is written.
+		recordUpdate(nodePoolDoc)
+		err = f.dbClient.SetNodePoolDoc(ctx, nodePoolDoc)
+		if err != nil {
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to update document for resource %s: %w", resourceID, err))
+			return
+		}
+		f.logger.Info(fmt.Sprintf("document updated for %s", resourceID))
+
+		applyNodePoolDocument(hcpNodePool, nodePoolDoc)
+
+		resp, err := json.Marshal(versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool))
+		if err != nil {
+			f.logger.Error(err.Error())
+			arm.WriteInternalServerError(writer)
+			return
+		}
+
+		writer.Header().Set(arm.HeaderNameETag, nodePoolDoc.ETag)
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		_, err = writer.Write(resp)
+		if err != nil {
+			f.logger.Error(err.Error())
+		}
+		return
+	}
+
+	csNodePool, err := f.BuildCSNodepool(ctx, hcpNodePool, updating)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	if updating {
+		csNodePool, err = f.clusterServiceClient.UpdateCSNodePool(ctx, clusterDoc.ClusterID, nodePoolDoc.NodePoolID, csNodePool)
+		if err != nil {
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to update node pool %s: %w", nodePoolDoc.NodePoolID, err))
+			return
+		}
+
+		// Cluster Service accepted the update, so record it even if the
+		// document changed since it was read.
+		err = database.UpdateNodePoolDoc(ctx, f.dbClient, resourceID, subscriptionID, func(updated *database.NodePoolDocument) bool {
+			recordUpdate(updated)
+			updated.ProvisioningState = arm.ProvisioningStateAccepted
+			nodePoolDoc = updated
+			return true
+		})
+		if err != nil {
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to update document for resource %s: %w", resourceID, err))
+			return
+		}
+		f.logger.Info(fmt.Sprintf("document updated for %s", resourceID))
+	} else {
+		csNodePool, err = f.clusterServiceClient.PostCSNodePool(ctx, clusterDoc.ClusterID, csNodePool)
+		if err != nil {
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to create node pool for %s: %w", resourceID, err))
+			return
+		}
+
+		nodePoolDoc.NodePoolID = csNodePool.ID()
+		nodePoolDoc.Tags = hcpNodePool.Tags
+		nodePoolDoc.ProvisioningState = arm.ProvisioningStateAccepted
+		err = f.dbClient.SetNodePoolDoc(ctx, nodePoolDoc)
+		if err != nil {
+			var conflictError *database.ConflictError
+			if errors.As(err, &conflictError) {
+				// A concurrent request created the resource first,
+				// so nothing refers to the node pool just created.
+				if deleteErr := f.clusterServiceClient.DeleteCSNodePool(ctx, clusterDoc.ClusterID, nodePoolDoc.NodePoolID); deleteErr != nil {
+					f.logger.Error(fmt.Sprintf("failed to delete unreferenced node pool %s: %v", nodePoolDoc.NodePoolID, deleteErr))
+				}
+			}
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to create document for resource %s: %w", resourceID, err))
+			return
+		}
+		f.logger.Info(fmt.Sprintf("document created for %s", resourceID))
+	}
+
+	hcpNodePool, err = f.nodePoolFromDocument(nodePoolDoc, csNodePool)
+	if err != nil {
+		// Should never happen currently
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	operationRequest := database.OperationRequestCreate
+	if updating {
+		operationRequest = database.OperationRequestUpdate
+	}
+	operationDoc := database.NewOperationDocument(operationRequest, subscriptionID, resourceID, nodePoolDoc.NodePoolID)
+	err = f.dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		f.logger.Error(fmt.Sprintf("failed to create operation document for resource %s: %v", resourceID, err))
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	resp, err := json.Marshal(versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool))
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.AddAsyncOperationHeaders(writer, request, operationDoc)
+	if nodePoolDoc.ETag != "" {
+		writer.Header().Set(arm.HeaderNameETag, nodePoolDoc.ETag)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	switch {
+	case request.Method == http.MethodPatch:
+		writer.WriteHeader(http.StatusAccepted)
+	case updating:
+		writer.WriteHeader(http.StatusOK)
+	default:
+		writer.WriteHeader(http.StatusCreated)
+	}
+
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
+
+func (f *Frontend) ArmNodePoolDelete(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmNodePoolDelete", versionedInterface))
+
+	// URL path is already lowercased by middleware.
+	resourceID := request.URL.Path
+	originalPath, _ := OriginalPathFromContext(ctx)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			if cloudError := CheckPreconditions(request, false, ""); cloudError != nil {
+				f.logger.Error(cloudError.Error())
+				arm.WriteCloudError(writer, cloudError)
+				return
+			}
+			f.logger.Info(fmt.Sprintf("node pool document cannot be deleted -- document not found for %s", resourceID))
+			writer.WriteHeader(http.StatusNoContent)
+		} else {
+			f.logger.Error(fmt.Sprintf("failed to fetch document for %s: %v", resourceID, err))
+			arm.WriteInternalServerError(writer)
+		}
+		return
+	}
+
+	if cloudError := CheckPreconditions(request, true, nodePoolDoc.ETag); cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
+	clusterDoc := f.getParentClusterDoc(writer, ctx, path.Dir(path.Dir(originalPath)), subscriptionID)
+	if clusterDoc == nil {
+		return
+	}
+
+	operationDoc := database.NewOperationDocument(database.OperationRequestDelete, subscriptionID, resourceID, nodePoolDoc.NodePoolID)
+
+	if nodePoolDoc.NodePoolID != "" {
+		// A node pool already gone from Cluster Service is left for the
+		// backend to clean up like any other deleted node pool.
+		err = f.clusterServiceClient.DeleteCSNodePool(ctx, clusterDoc.ClusterID, nodePoolDoc.NodePoolID)
+		if err != nil && !ocm.IsNotFound(err) {
+			f.writeClusterServiceError(writer, request, fmt.Errorf("failed to delete node pool %s: %w", nodePoolDoc.NodePoolID, err))
+			return
+		}
+
+		// Cluster Service accepted the deletion, so record it even if the
+		// document changed since it was read. The backend removes the
+		// document once Cluster Service finishes deleting the node pool.
+		err = database.UpdateNodePoolDoc(ctx, f.dbClient, resourceID, subscriptionID, func(doc *database.NodePoolDocument) bool {
+			doc.ProvisioningState = arm.ProvisioningStateDeleting
+			return true
+		})
+		if err != nil {
+			f.writeDocumentWriteError(writer, request, fmt.Errorf("failed to update document for resource %s: %w", resourceID, err))
+			return
+		}
+	} else {
+		// Nothing to delete from Cluster Service.
+		operationDoc.Status = arm.ProvisioningStateSucceeded
+
+		err = f.dbClient.DeleteNodePoolDoc(ctx, resourceID, subscriptionID)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			f.logger.Error(err.Error())
+			arm.WriteInternalServerError(writer)
+			return
+		}
+		f.logger.Info(fmt.Sprintf("document deleted for resource %s", resourceID))
+	}
+
+	err = f.dbClient.CreateOperationDoc(ctx, operationDoc)
+	if err != nil {
+		f.logger.Error(fmt.Sprintf("failed to create operation document for resource %s: %v", resourceID, err))
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.AddAsyncOperationHeaders(writer, request, operationDoc)
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// writeResourceNotFoundError writes a 404 response for a resource whose
+// document does not exist.
+func writeResourceNotFoundError(writer http.ResponseWriter, resourceID string) {
+	parsed, err := azcorearm.ParseResourceID(resourceID)
+	if err != nil {
+		arm.WriteError(
+			writer, http.StatusNotFound,
+			arm.CloudErrorCodeNotFound, resourceID,
+			"The resource '%s' could not be found.", resourceID)
+		return
+	}
+	arm.WriteError(
+		writer, http.StatusNotFound,
+		arm.CloudErrorCodeResourceNotFound, resourceID,
+		"The Resource '%s/%s' under resource group '%s' was not found.",
+		parsed.ResourceType, parsed.Name, parsed.ResourceGroupName)
+}
@@ -0,0 +1,113 @@
+package frontend
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestNodePoolParentClusterNotFound(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+	const nodePoolPath = clusterPath + "/nodePools/myNodePool"
+
+	tests := []struct {
+		name               string
+		method             string
+		urlPath            string
+		nodePoolDoc        bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "List node pools",
+			method:             http.MethodGet,
+			urlPath:            clusterPath + "/nodePools",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Read node pool",
+			method:             http.MethodGet,
+			urlPath:            nodePoolPath,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Create node pool",
+			method:             http.MethodPut,
+			urlPath:            nodePoolPath,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Update node pool",
+			method:             http.MethodPatch,
+			urlPath:            nodePoolPath,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Delete node pool",
+			method:             http.MethodDelete,
+			urlPath:            nodePoolPath,
+			nodePoolDoc:        true,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Delete missing node pool",
+			method:             http.MethodDelete,
+			urlPath:            nodePoolPath,
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &Frontend{
+				dbClient: database.NewCache(),
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				metrics:  NewPrometheusEmitter(),
+				region:   "eastus",
+			}
+
+			err := f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+				PartitionKey: subscriptionID,
+				Subscription: &arm.Subscription{State: arm.Registered},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.nodePoolDoc {
+				err = f.dbClient.SetNodePoolDoc(context.TODO(), &database.NodePoolDocument{
+					Key:          strings.ToLower(nodePoolPath),
+					PartitionKey: subscriptionID,
+					NodePoolID:   "mynodepool",
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ts := newTestServer(t, f)
+
+			req, err := http.NewRequest(test.method, ts.URL+test.urlPath+"?api-version=2024-06-10-preview", strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(arm.HeaderNameARMResourceSystemData, "{}")
+
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rs.StatusCode != test.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", test.expectedStatusCode, rs.StatusCode)
+			}
+		})
+	}
+}
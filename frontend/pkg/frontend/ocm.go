@@ -2,12 +2,20 @@ package frontend
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
 
 	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
 	configv1 "github.com/openshift/api/config/v1"
 
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
 	"github.com/Azure/ARO-HCP/internal/api"
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
@@ -19,8 +27,91 @@ const (
 	csHypershifEnabled bool   = true
 	csMultiAzEnabled   bool   = true
 	csCCSEnabled       bool   = true
+
+	// csVersionsSearch restricts Cluster Service versions to those
+	// enabled for the hypershift (hosted control plane) product.
+	csVersionsSearch string = "enabled = 'true' AND hosted_control_plane_enabled = 'true'"
+
+	// csListPageSize is the number of items requested per page when
+	// listing from Cluster Service.
+	csListPageSize int = 100
 )
 
+// clusterStateProvisioningStates maps Cluster Service cluster states to
+// ARM provisioning states.
+//
+//	Cluster Service state | ProvisioningState | Meaning
+//	----------------------+-------------------+----------------------------------------
+//	pending               | Accepted          | Request queued, not yet started
+//	validating            | Accepted          | Preflight checks are running
+//	waiting               | Accepted          | Waiting for user-provided resources
+//	installing            | Provisioning      | Control plane is being installed
+//	ready                 | Succeeded         | Cluster is available
+//	error                 | Failed            | Installation or operation failed
+//	uninstalling          | Deleting          | Cluster is being deleted
+//	hibernating           | Succeeded         | Cluster is stable but hibernated
+//	powering_down         | Updating          | Cluster is entering hibernation
+//	resuming              | Updating          | Cluster is leaving hibernation
+//	unknown               | Failed            | Cluster Service cannot determine state
+//
+// A state missing from this table yields an empty ProvisioningState.
+var clusterStateProvisioningStates = map[cmv1.ClusterState]arm.ProvisioningState{
+	cmv1.ClusterStatePending:      arm.ProvisioningStateAccepted,
+	cmv1.ClusterStateValidating:   arm.ProvisioningStateAccepted,
+	cmv1.ClusterStateWaiting:      arm.ProvisioningStateAccepted,
+	cmv1.ClusterStateInstalling:   arm.ProvisioningStateProvisioning,
+	cmv1.ClusterStateReady:        arm.ProvisioningStateSucceeded,
+	cmv1.ClusterStateError:        arm.ProvisioningStateFailed,
+	cmv1.ClusterStateUninstalling: arm.ProvisioningStateDeleting,
+	cmv1.ClusterStateHibernating:  arm.ProvisioningStateSucceeded,
+	cmv1.ClusterStatePoweringDown: arm.ProvisioningStateUpdating,
+	cmv1.ClusterStateResuming:     arm.ProvisioningStateUpdating,
+	cmv1.ClusterStateUnknown:      arm.ProvisioningStateFailed,
+}
+
+// ConvertCSClusterStateToProvisioningState translates a Cluster Service
+// cluster state to an ARM provisioning state.
+func ConvertCSClusterStateToProvisioningState(state cmv1.ClusterState) arm.ProvisioningState {
+	return clusterStateProvisioningStates[state]
+}
+
+// ConvertCSNodePoolStatusToProvisioningState derives an ARM provisioning
+// state for a Cluster Service node pool. Node pools carry no lifecycle
+// state, only a replica count and a message explaining why the replicas
+// are not ready, so:
+//
+//	Node pool status                                    | ProvisioningState
+//	----------------------------------------------------+------------------
+//	No status reported yet                              | Accepted
+//	Current replicas within the desired range           | Succeeded
+//	Current replicas outside the range, with a message  | Failed
+//	Current replicas outside the desired range          | Provisioning
+//
+// The desired range is the autoscaling range if set, or else the fixed
+// replica count.
+func ConvertCSNodePoolStatusToProvisioningState(np *cmv1.NodePool) arm.ProvisioningState {
+	status, ok := np.GetStatus()
+	if !ok {
+		return arm.ProvisioningStateAccepted
+	}
+	current, ok := status.GetCurrentReplicas()
+
+	minReplicas, maxReplicas := np.Replicas(), np.Replicas()
+	if autoscaling, ok := np.GetAutoscaling(); ok {
+		minReplicas, maxReplicas = autoscaling.MinReplica(), autoscaling.MaxReplica()
+	}
+
+	switch {
+	case ok && current >= minReplicas && current <= maxReplicas:
+		return arm.ProvisioningStateSucceeded
+	case status.Message() != "":
+		return arm.ProvisioningStateFailed
+	case !ok:
+		return arm.ProvisioningStateAccepted
+	}
+	return arm.ProvisioningStateProvisioning
+}
+
 // ConvertCStoHCPOpenShiftCluster converts a CS Cluster object into HCPOpenShiftCluster object
 func (f *Frontend) ConvertCStoHCPOpenShiftCluster(systemData *arm.SystemData, cluster *cmv1.Cluster) (*api.HCPOpenShiftCluster, error) {
 
@@ -32,7 +123,7 @@ func (f *Frontend) ConvertCStoHCPOpenShiftCluster(systemData *arm.SystemData, cl
 	hcpcluster := &api.HCPOpenShiftCluster{
 		TrackedResource: arm.TrackedResource{
 			Location: cluster.Region().ID(),
-			Tags:     nil, // TODO: OCM should support cluster.Azure().Tags(),
+			Tags:     nil, // Tags are stored in the cluster document
 			Resource: arm.Resource{
 				ID:         resourceID,
 				Name:       resourceName,
@@ -41,7 +132,7 @@ func (f *Frontend) ConvertCStoHCPOpenShiftCluster(systemData *arm.SystemData, cl
 			},
 		},
 		Properties: api.HCPOpenShiftClusterProperties{
-			// ProvisioningState: cluster.State(), // TODO: align with OCM on ProvisioningState
+			ProvisioningState: ConvertCSClusterStateToProvisioningState(cluster.State()),
 			Spec: api.ClusterSpec{
 				Version: api.VersionProfile{
 					ID:                cluster.Version().ID(),
@@ -101,8 +192,100 @@ func (f *Frontend) ConvertCStoHCPOpenShiftCluster(systemData *arm.SystemData, cl
 	return hcpcluster, nil
 }
 
-// BuildCSCluster creates a CS Cluster object from an HCPOpenShiftCluster object
-func (f *Frontend) BuildCSCluster(ctx context.Context, hcpCluster *api.HCPOpenShiftCluster) (*cmv1.Cluster, error) {
+// clusterFromDocument converts a CS Cluster object into an HCPOpenShiftCluster
+// object and applies the fields that only the cluster document stores.
+func (f *Frontend) clusterFromDocument(doc *database.HCPOpenShiftClusterDocument, csCluster *cmv1.Cluster) (*api.HCPOpenShiftCluster, error) {
+	hcpCluster, err := f.ConvertCStoHCPOpenShiftCluster(doc.SystemData, csCluster)
+	if err != nil {
+		return nil, err
+	}
+	applyClusterDocument(hcpCluster, doc)
+	return hcpCluster, nil
+}
+
+// applyClusterDocument sets the fields of an HCPOpenShiftCluster object
+// that only the cluster document stores.
+func applyClusterDocument(hcpCluster *api.HCPOpenShiftCluster, doc *database.HCPOpenShiftClusterDocument) {
+	hcpCluster.SystemData = doc.SystemData
+	hcpCluster.ETag = doc.ETag
+	hcpCluster.Identity = doc.Identity
+	hcpCluster.Tags = doc.Tags
+	if doc.ProvisioningState != "" {
+		hcpCluster.Properties.ProvisioningState = doc.ProvisioningState
+	}
+}
+
+// ConvertCStoHCPOpenShiftVersion converts a CS Version object into an HCPOpenShiftVersion object
+func (f *Frontend) ConvertCStoHCPOpenShiftVersion(subscriptionID, location string, version *cmv1.Version) *api.HCPOpenShiftVersion {
+	return &api.HCPOpenShiftVersion{
+		Resource: arm.Resource{
+			ID: path.Join(
+				"/subscriptions", subscriptionID,
+				"providers", api.ProviderNamespace,
+				"locations", location,
+				api.VersionResourceTypeName, version.RawID()),
+			Name: version.RawID(),
+			Type: api.VersionResourceType,
+		},
+		Properties: api.HCPOpenShiftVersionProperties{
+			ProvisioningState: arm.ProvisioningStateSucceeded,
+			ClusterVersion:    version.RawID(),
+		},
+	}
+}
+
+// csStringLiteral quotes a value for a Cluster Service search expression.
+// Single quotes are doubled so the value can never escape its string literal.
+func csStringLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// csIDSearch returns a Cluster Service search expression that matches
+// any of the given IDs.
+func csIDSearch(ids []string) string {
+	quoted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		quoted = append(quoted, csStringLiteral(id))
+	}
+	return "id in (" + strings.Join(quoted, ", ") + ")"
+}
+
+// getCSClusters fetches the Cluster Service clusters with the given IDs
+// in batches of csListPageSize and returns them keyed by ID. IDs with no
+// cluster in Cluster Service are absent from the result.
+func (f *Frontend) getCSClusters(ctx context.Context, clusterIDs []string) (map[string]*cmv1.Cluster, error) {
+	clusters := make(map[string]*cmv1.Cluster, len(clusterIDs))
+
+	for start := 0; start < len(clusterIDs); start += csListPageSize {
+		batch := clusterIDs[start:min(start+csListPageSize, len(clusterIDs))]
+		search := csIDSearch(batch)
+		for page := 1; ; page++ {
+			items, total, err := f.clusterServiceClient.ListCSClusters(ctx, search, page, csListPageSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list clusters from clusters-service: %w", err)
+			}
+			for _, cluster := range items {
+				if slices.Contains(batch, cluster.ID()) {
+					clusters[cluster.ID()] = cluster
+				}
+			}
+			if len(items) == 0 || page*csListPageSize >= total {
+				break
+			}
+		}
+	}
+
+	return clusters, nil
+}
+
+// BuildCSCluster creates a CS Cluster object from an HCPOpenShiftCluster object.
+// When updating the current cluster, only fields with update visibility are
+// included.
+func (f *Frontend) BuildCSCluster(ctx context.Context, hcpCluster, current *api.HCPOpenShiftCluster) (*cmv1.Cluster, error) {
+	if current != nil {
+		return buildCSClusterUpdate(hcpCluster, current)
+	}
+
 	originalPath, err := OriginalPathFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not get original path: %w", err)
@@ -131,6 +314,7 @@ func (f *Frontend) BuildCSCluster(ctx context.Context, hcpCluster *api.HCPOpenSh
 
 	clusterBuilder := cmv1.NewCluster().
 		Name(hcpCluster.Name).
+		DomainPrefix(hcpCluster.Properties.Spec.DNS.BaseDomainPrefix).
 		Flavour(cmv1.NewFlavour().
 			ID(hcpCluster.Type)).
 		Version(cmv1.NewVersion().
@@ -171,6 +355,14 @@ func (f *Frontend) BuildCSCluster(ctx context.Context, hcpCluster *api.HCPOpenSh
 		CCS(cmv1.NewCCS().Enabled(csCCSEnabled)).
 		Properties(additionalProperties)
 
+	proxy := hcpCluster.Properties.Spec.Proxy
+	if proxy.HTTPProxy != "" || proxy.HTTPSProxy != "" || proxy.NoProxy != "" {
+		clusterBuilder = clusterBuilder.Proxy(cmv1.NewProxy().
+			HTTPProxy(proxy.HTTPProxy).
+			HTTPSProxy(proxy.HTTPSProxy).
+			NoProxy(proxy.NoProxy))
+	}
+
 	cluster, err := clusterBuilder.Build()
 	if err != nil {
 		return nil, err
@@ -178,12 +370,66 @@ func (f *Frontend) BuildCSCluster(ctx context.Context, hcpCluster *api.HCPOpenSh
 	return cluster, nil
 }
 
+// buildCSClusterUpdate creates a sparse CS Cluster object for a PATCH request
+// containing only the HCPOpenShiftCluster fields with update visibility. The
+// proxy and trust bundle are only included if they differ from the current
+// cluster, so an update that leaves them alone does not reconfigure them.
+func buildCSClusterUpdate(hcpCluster, current *api.HCPOpenShiftCluster) (*cmv1.Cluster, error) {
+	clusterBuilder := cmv1.NewCluster().
+		Version(cmv1.NewVersion().
+			ID(hcpCluster.Properties.Spec.Version.ID)).
+		DisableUserWorkloadMonitoring(hcpCluster.Properties.Spec.DisableUserWorkloadMonitoring)
+
+	proxy, currentProxy := hcpCluster.Properties.Spec.Proxy, current.Properties.Spec.Proxy
+	if proxy.HTTPProxy != currentProxy.HTTPProxy || proxy.HTTPSProxy != currentProxy.HTTPSProxy || proxy.NoProxy != currentProxy.NoProxy {
+		clusterBuilder = clusterBuilder.Proxy(cmv1.NewProxy().
+			HTTPProxy(proxy.HTTPProxy).
+			HTTPSProxy(proxy.HTTPSProxy).
+			NoProxy(proxy.NoProxy))
+	}
+	if proxy.TrustedCA != currentProxy.TrustedCA {
+		clusterBuilder = clusterBuilder.AdditionalTrustBundle(proxy.TrustedCA)
+	}
+
+	return clusterBuilder.Build()
+}
+
+// nodePoolFromDocument converts a CS Node Pool object into an
+// HCPOpenShiftClusterNodePool object and applies the fields that only the
+// node pool document stores.
+func (f *Frontend) nodePoolFromDocument(doc *database.NodePoolDocument, csNodePool *cmv1.NodePool) (*api.HCPOpenShiftClusterNodePool, error) {
+	hcpNodePool, err := f.ConvertCStoNodepool(path.Dir(path.Dir(doc.Key)), doc.SystemData, csNodePool)
+	if err != nil {
+		return nil, err
+	}
+	applyNodePoolDocument(hcpNodePool, doc)
+	return hcpNodePool, nil
+}
+
+// applyNodePoolDocument is like applyClusterDocument for node pools.
+func applyNodePoolDocument(hcpNodePool *api.HCPOpenShiftClusterNodePool, doc *database.NodePoolDocument) {
+	hcpNodePool.SystemData = doc.SystemData
+	hcpNodePool.ETag = doc.ETag
+	hcpNodePool.Tags = doc.Tags
+	if doc.ProvisioningState != "" {
+		hcpNodePool.Properties.ProvisioningState = doc.ProvisioningState
+	}
+}
+
 // ConvertCStoNodepool converts a CS Node Pool object into HCPOpenShiftClusterNodePool object
-func (f *Frontend) ConvertCStoNodepool(ctx context.Context, systemData *arm.SystemData, np *cmv1.NodePool) (*api.HCPOpenShiftClusterNodePool, error) {
+func (f *Frontend) ConvertCStoNodepool(clusterResourceID string, systemData *arm.SystemData, np *cmv1.NodePool) (*api.HCPOpenShiftClusterNodePool, error) {
 	nodePool := &api.HCPOpenShiftClusterNodePool{
-		TrackedResource: arm.TrackedResource{}, // TODO: Implement
+		TrackedResource: arm.TrackedResource{
+			Location: f.region,
+			Resource: arm.Resource{
+				ID:         path.Join(clusterResourceID, api.NodePoolResourceTypeName, np.ID()),
+				Name:       np.ID(),
+				Type:       api.NodePoolResourceType,
+				SystemData: systemData,
+			},
+		},
 		Properties: api.HCPOpenShiftClusterNodePoolProperties{
-			// ProvisioningState: np.Status(), // TODO: Align with OCM on aligning with ProvisioningState
+			ProvisioningState: ConvertCSNodePoolStatusToProvisioningState(np),
 			Spec: api.NodePoolSpec{
 				Version: api.VersionProfile{
 					ID:                np.Version().ID(),
@@ -225,63 +471,187 @@ func (f *Frontend) ConvertCStoNodepool(ctx context.Context, systemData *arm.Syst
 	return nodePool, nil
 }
 
-// BuildCSNodepool creates a CS Node Pool object from an HCPOpenShiftClusterNodePool object
-func (f *Frontend) BuildCSNodepool(ctx context.Context, nodepool *api.HCPOpenShiftClusterNodePool) (*cmv1.NodePool, error) {
-	azureNodepool := cmv1.NewAzureNodePool().
-		VMSize(nodepool.Properties.Spec.Platform.VMSize).
-		ResourceName(nodepool.Name).
-		EphemeralOSDiskEnabled(nodepool.Properties.Spec.Platform.EphemeralOSDisk).
-		OSDiskSizeGibibytes(int(nodepool.Properties.Spec.Platform.DiskSizeGiB)).
-		OSDiskStorageAccountType(nodepool.Properties.Spec.Platform.DiskStorageAccountType)
-
-	npBuilder := cmv1.NewNodePool().
-		AutoRepair(nodepool.Properties.Spec.AutoRepair).
-		Autoscaling(cmv1.NewNodePoolAutoscaling().
+// BuildCSNodepool creates a CS Node Pool object from an HCPOpenShiftClusterNodePool object.
+// When updating, only fields Cluster Service allows to change are included.
+func (f *Frontend) BuildCSNodepool(ctx context.Context, nodepool *api.HCPOpenShiftClusterNodePool, updating bool) (*cmv1.NodePool, error) {
+	npBuilder := cmv1.NewNodePool()
+
+	if !updating {
+		npBuilder = npBuilder.
+			ID(nodepool.Name).
+			AutoRepair(nodepool.Properties.Spec.AutoRepair).
+			Subnet(nodepool.Properties.Spec.Platform.SubnetID).
+			AzureNodePool(cmv1.NewAzureNodePool().
+				VMSize(nodepool.Properties.Spec.Platform.VMSize).
+				ResourceName(nodepool.Name).
+				EphemeralOSDiskEnabled(nodepool.Properties.Spec.Platform.EphemeralOSDisk).
+				OSDiskSizeGibibytes(int(nodepool.Properties.Spec.Platform.DiskSizeGiB)).
+				OSDiskStorageAccountType(nodepool.Properties.Spec.Platform.DiskStorageAccountType))
+		if nodepool.Properties.Spec.Platform.AvailabilityZone != "" {
+			npBuilder = npBuilder.AvailabilityZone(nodepool.Properties.Spec.Platform.AvailabilityZone)
+		}
+	}
+
+	// Cluster Service rejects node pools that specify both a fixed
+	// replica count and an autoscaling range.
+	if nodepool.Properties.Spec.Autoscaling.Max > 0 {
+		npBuilder = npBuilder.Autoscaling(cmv1.NewNodePoolAutoscaling().
 			MinReplica(int(nodepool.Properties.Spec.Autoscaling.Min)).
-			MaxReplica(int(nodepool.Properties.Spec.Autoscaling.Max))).
-		Labels(nodepool.Properties.Spec.Labels).
-		Replicas(int(nodepool.Properties.Spec.Replicas)).
-		Subnet(nodepool.Properties.Spec.Platform.SubnetID).
-		TuningConfigs(nodepool.Properties.Spec.TuningConfigs...).
-		Version(cmv1.NewVersion().
-			ID(nodepool.Properties.Spec.Version.ID).
-			ChannelGroup(nodepool.Properties.Spec.Version.ChannelGroup).
-			AvailableUpgrades(nodepool.Properties.Spec.Version.AvailableUpgrades...)).
-		AzureNodePool(azureNodepool)
+			MaxReplica(int(nodepool.Properties.Spec.Autoscaling.Max)))
+	} else {
+		npBuilder = npBuilder.Replicas(int(nodepool.Properties.Spec.Replicas))
+	}
 
+	taintBuilders := make([]*cmv1.TaintBuilder, 0, len(nodepool.Properties.Spec.Taints))
 	for _, t := range nodepool.Properties.Spec.Taints {
-		npBuilder = npBuilder.Taints(cmv1.NewTaint().
+		taintBuilders = append(taintBuilders, cmv1.NewTaint().
 			Effect(string(t.Effect)).
 			Key(t.Key).
 			Value(t.Value))
 	}
 
+	npBuilder = npBuilder.
+		Labels(nodepool.Properties.Spec.Labels).
+		Taints(taintBuilders...).
+		TuningConfigs(nodepool.Properties.Spec.TuningConfigs...).
+		Version(cmv1.NewVersion().
+			ID(nodepool.Properties.Spec.Version.ID).
+			ChannelGroup(nodepool.Properties.Spec.Version.ChannelGroup).
+			AvailableUpgrades(nodepool.Properties.Spec.Version.AvailableUpgrades...))
+
 	return npBuilder.Build()
 }
 
-// GetCSCluster creates and sends a GET request to fetch a cluster from Clusters Service
-func (f *Frontend) GetCSCluster(clusterID string) (*cmv1.ClusterGetResponse, error) {
-	cluster, err := f.clusterServiceConfig.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).Get().Send()
-	if err != nil {
-		return nil, err
-	}
-	return cluster, nil
+// csAttributeTargets maps Cluster Service attribute names, as they appear
+// in error reasons, to the corresponding field in the ARM request body.
+var csAttributeTargets = map[string]string{
+	"name":                             "name",
+	"version.id":                       "properties.spec.version.id",
+	"version.channel_group":            "properties.spec.version.channelGroup",
+	"domain_prefix":                    "properties.spec.dns.baseDomainPrefix",
+	"network.type":                     "properties.spec.network.networkType",
+	"network.pod_cidr":                 "properties.spec.network.podCidr",
+	"network.service_cidr":             "properties.spec.network.serviceCidr",
+	"network.machine_cidr":             "properties.spec.network.machineCidr",
+	"network.host_prefix":              "properties.spec.network.hostPrefix",
+	"api.listening":                    "properties.spec.api.visibility",
+	"fips":                             "properties.spec.fips",
+	"etcd_encryption":                  "properties.spec.etcdEncryption",
+	"disable_user_workload_monitoring": "properties.spec.disableUserWorkloadMonitoring",
+	"proxy.http_proxy":                 "properties.spec.proxy.httpProxy",
+	"proxy.https_proxy":                "properties.spec.proxy.httpsProxy",
+	"proxy.no_proxy":                   "properties.spec.proxy.noProxy",
+	"additional_trust_bundle":          "properties.spec.proxy.trustedCa",
+
+	"azure.managed_resource_group_name":        "properties.spec.platform.managedResourceGroup",
+	"azure.subnet_resource_id":                 "properties.spec.platform.subnetId",
+	"azure.network_security_group_resource_id": "properties.spec.platform.networkSecurityGroupId",
+
+	"replicas":                               "properties.spec.replicas",
+	"auto_repair":                            "properties.spec.autoRepair",
+	"autoscaling.min_replica":                "properties.spec.autoScaling.min",
+	"autoscaling.max_replica":                "properties.spec.autoScaling.max",
+	"labels":                                 "properties.spec.labels",
+	"taints":                                 "properties.spec.taints",
+	"tuning_configs":                         "properties.spec.tuningConfigs",
+	"subnet":                                 "properties.spec.platform.subnetId",
+	"availability_zone":                      "properties.spec.platform.availabilityZone",
+	"azure_node_pool.vm_size":                "properties.spec.platform.vmSize",
+	"azure_node_pool.os_disk_size_gibibytes": "properties.spec.platform.diskSizeGiB",
+	"azure_node_pool.os_disk_storage_account_type": "properties.spec.platform.diskStorageAccountType",
+	"azure_node_pool.ephemeral_os_disk_enabled":    "properties.spec.platform.ephemeralOsDisk",
 }
 
-// PostCSCluster creates and sends a POST request to create a cluster in Clusters Service
-func (f *Frontend) PostCSCluster(cluster *cmv1.Cluster) (*cmv1.ClustersAddResponse, error) {
-	resp, err := f.clusterServiceConfig.Conn.ClustersMgmt().V1().Clusters().Add().Body(cluster).Send()
-	if err != nil {
-		return nil, err
+// csReasonTargets maps the leading words of Cluster Service error reasons
+// that name a field without quoting its attribute name.
+var csReasonTargets = []struct {
+	prefix string
+	target string
+}{
+	{"Version ", "properties.spec.version.id"},
+	{"Channel group ", "properties.spec.version.channelGroup"},
+	{"Cluster name ", "name"},
+	{"Node pool name ", "name"},
+}
+
+// csQuotedPattern matches the single-quoted words of an error reason.
+var csQuotedPattern = regexp.MustCompile(`'([^']*)'`)
+
+// csErrorTarget returns the ARM request body field that a Cluster
+// Service error reason refers to, or an empty string if it cannot be
+// determined.
+func csErrorTarget(reason string) string {
+	for _, match := range csQuotedPattern.FindAllStringSubmatch(reason, -1) {
+		if target, ok := csAttributeTargets[match[1]]; ok {
+			return target
+		}
 	}
-	return resp, nil
+	for _, entry := range csReasonTargets {
+		if strings.HasPrefix(reason, entry.prefix) {
+			return entry.target
+		}
+	}
+	return ""
 }
 
-// DeleteCSCluster creates and sends a DELETE request to delete a cluster from Clusters Service
-func (f *Frontend) DeleteCSCluster(clusterID string) (*cmv1.ClusterDeleteResponse, error) {
-	resp, err := f.clusterServiceConfig.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).Delete().Send()
-	if err != nil {
-		return nil, err
+// CSErrorToCloudError converts an error returned by Cluster Service into
+// a CloudError for the resource being acted on. Errors that are not from
+// Cluster Service, or that stem from the frontend's own misuse of it,
+// become internal server errors.
+func CSErrorToCloudError(err error, resourceID string) *arm.CloudError {
+	var ocmError *ocmerrors.Error
+	if !errors.As(err, &ocmError) {
+		return arm.NewCloudError(
+			http.StatusInternalServerError,
+			arm.CloudErrorCodeInternalServerError, "",
+			"Internal server error.")
+	}
+
+	switch ocmError.Status() {
+	case http.StatusBadRequest:
+		target := csErrorTarget(ocmError.Reason())
+		code := arm.CloudErrorCodeInvalidRequestContent
+		if target != "" {
+			code = arm.CloudErrorCodeInvalidParameter
+		}
+		return arm.NewCloudError(
+			http.StatusBadRequest,
+			code, target,
+			"%s", ocmError.Reason())
+	case http.StatusNotFound:
+		return arm.NewCloudError(
+			http.StatusNotFound,
+			arm.CloudErrorCodeNotFound, resourceID,
+			"The resource '%s' could not be found.", resourceID)
+	case http.StatusConflict:
+		return arm.NewCloudError(
+			http.StatusConflict,
+			arm.CloudErrorCodeConflict, resourceID,
+			"%s", ocmError.Reason())
+	case http.StatusTooManyRequests:
+		return arm.NewCloudError(
+			http.StatusTooManyRequests,
+			arm.CloudErrorCodeTooManyRequests, "",
+			"Too many requests. Retry the request later.")
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return arm.NewCloudError(
+			http.StatusServiceUnavailable,
+			arm.CloudErrorCodeServiceUnavailable, "",
+			"The service is temporarily unavailable. Retry the request later.")
+	default:
+		// Includes 401 and 403, which indicate a problem with the
+		// frontend's own credentials rather than the request.
+		return arm.NewCloudError(
+			http.StatusInternalServerError,
+			arm.CloudErrorCodeInternalServerError, "",
+			"Internal server error.")
 	}
-	return resp, nil
+}
+
+// writeClusterServiceError logs a failed Cluster Service request and
+// writes the CloudError it maps to.
+func (f *Frontend) writeClusterServiceError(writer http.ResponseWriter, request *http.Request, err error) {
+	originalPath, _ := OriginalPathFromContext(request.Context())
+	f.logger.Error(err.Error())
+	arm.WriteCloudError(writer, CSErrorToCloudError(err, originalPath))
 }
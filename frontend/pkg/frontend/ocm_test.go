@@ -0,0 +1,383 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/csfake"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestBuildCSClusterUpdate(t *testing.T) {
+	f := &Frontend{region: "eastus"}
+
+	hcpCluster := api.NewDefaultHCPOpenShiftCluster()
+	hcpCluster.Name = "mycluster"
+	hcpCluster.Properties.Spec.Version.ID = "4.16.0"
+	hcpCluster.Properties.Spec.Version.ChannelGroup = "stable"
+	hcpCluster.Properties.Spec.DNS.BaseDomainPrefix = "prefix"
+	hcpCluster.Properties.Spec.DisableUserWorkloadMonitoring = true
+	hcpCluster.Properties.Spec.Proxy.HTTPProxy = "http://proxy.example.com"
+	hcpCluster.Properties.Spec.Proxy.TrustedCA = "trusted-ca"
+	hcpCluster.Properties.Spec.Platform.SubnetID = "subnet-id"
+
+	current := api.NewDefaultHCPOpenShiftCluster()
+
+	// The context is only consulted when creating a cluster.
+	csCluster, err := f.BuildCSCluster(context.TODO(), hcpCluster, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if csCluster.Version().ID() != "4.16.0" {
+		t.Errorf("expected version %q, got %q", "4.16.0", csCluster.Version().ID())
+	}
+	if _, ok := csCluster.Version().GetChannelGroup(); ok {
+		t.Error("expected channel group to be unset")
+	}
+	if !csCluster.DisableUserWorkloadMonitoring() {
+		t.Error("expected user workload monitoring to be disabled")
+	}
+	if csCluster.Proxy().HTTPProxy() != "http://proxy.example.com" {
+		t.Errorf("expected HTTP proxy %q, got %q", "http://proxy.example.com", csCluster.Proxy().HTTPProxy())
+	}
+	if csCluster.AdditionalTrustBundle() != "trusted-ca" {
+		t.Errorf("expected trust bundle %q, got %q", "trusted-ca", csCluster.AdditionalTrustBundle())
+	}
+
+	// Fields without update visibility must not be sent.
+	if _, ok := csCluster.GetName(); ok {
+		t.Error("expected name to be unset")
+	}
+	if _, ok := csCluster.GetAzure(); ok {
+		t.Error("expected Azure settings to be unset")
+	}
+	if _, ok := csCluster.GetNetwork(); ok {
+		t.Error("expected network settings to be unset")
+	}
+	if _, ok := csCluster.GetRegion(); ok {
+		t.Error("expected region to be unset")
+	}
+
+	// An unchanged proxy must not be sent.
+	current.Properties.Spec.Proxy = hcpCluster.Properties.Spec.Proxy
+	csCluster, err = f.BuildCSCluster(context.TODO(), hcpCluster, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := csCluster.GetProxy(); ok {
+		t.Error("expected unchanged proxy to be unset")
+	}
+	if _, ok := csCluster.GetAdditionalTrustBundle(); ok {
+		t.Error("expected unchanged trust bundle to be unset")
+	}
+}
+
+func TestBuildCSNodepool(t *testing.T) {
+	f := &Frontend{}
+
+	nodePool := &api.HCPOpenShiftClusterNodePool{
+		TrackedResource: arm.TrackedResource{
+			Resource: arm.Resource{Name: "mynodepool"},
+		},
+		Properties: api.HCPOpenShiftClusterNodePoolProperties{
+			Spec: api.NodePoolSpec{
+				Platform: api.NodePoolPlatformProfile{VMSize: "Standard_D8s_v3"},
+				Replicas: 3,
+				Taints: []*api.Taint{
+					{Effect: api.EffectNoSchedule, Key: "key1"},
+					{Effect: api.EffectNoExecute, Key: "key2", Value: "value2"},
+				},
+			},
+		},
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		csNodePool, err := f.BuildCSNodepool(context.TODO(), nodePool, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if csNodePool.ID() != "mynodepool" {
+			t.Errorf("expected ID %q, got %q", "mynodepool", csNodePool.ID())
+		}
+		if csNodePool.AzureNodePool().VMSize() != "Standard_D8s_v3" {
+			t.Errorf("expected VM size %q, got %q", "Standard_D8s_v3", csNodePool.AzureNodePool().VMSize())
+		}
+		if len(csNodePool.Taints()) != 2 {
+			t.Errorf("expected 2 taints, got %d", len(csNodePool.Taints()))
+		}
+		if _, ok := csNodePool.GetAutoscaling(); ok {
+			t.Error("expected autoscaling to be unset")
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		csNodePool, err := f.BuildCSNodepool(context.TODO(), nodePool, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := csNodePool.GetID(); ok {
+			t.Error("expected ID to be unset")
+		}
+		if _, ok := csNodePool.GetAzureNodePool(); ok {
+			t.Error("expected Azure node pool to be unset")
+		}
+		if csNodePool.Replicas() != 3 {
+			t.Errorf("expected 3 replicas, got %d", csNodePool.Replicas())
+		}
+	})
+}
+
+func TestConvertClusterStateToProvisioningState(t *testing.T) {
+	tests := []struct {
+		state    cmv1.ClusterState
+		expected arm.ProvisioningState
+	}{
+		{cmv1.ClusterStatePending, arm.ProvisioningStateAccepted},
+		{cmv1.ClusterStateValidating, arm.ProvisioningStateAccepted},
+		{cmv1.ClusterStateWaiting, arm.ProvisioningStateAccepted},
+		{cmv1.ClusterStateInstalling, arm.ProvisioningStateProvisioning},
+		{cmv1.ClusterStateReady, arm.ProvisioningStateSucceeded},
+		{cmv1.ClusterStateError, arm.ProvisioningStateFailed},
+		{cmv1.ClusterStateUninstalling, arm.ProvisioningStateDeleting},
+		{cmv1.ClusterStateHibernating, arm.ProvisioningStateSucceeded},
+		{cmv1.ClusterStatePoweringDown, arm.ProvisioningStateUpdating},
+		{cmv1.ClusterStateResuming, arm.ProvisioningStateUpdating},
+		{cmv1.ClusterStateUnknown, arm.ProvisioningStateFailed},
+		{cmv1.ClusterState("bogus"), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.state), func(t *testing.T) {
+			actual := ConvertCSClusterStateToProvisioningState(test.state)
+			if actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+
+	// Every state in the table must be covered above.
+	if len(tests)-1 != len(clusterStateProvisioningStates) {
+		t.Errorf("expected %d mapped states, got %d", len(tests)-1, len(clusterStateProvisioningStates))
+	}
+}
+
+func TestConvertNodePoolStatusToProvisioningState(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *cmv1.NodePoolBuilder
+		expected arm.ProvisioningState
+	}{
+		{
+			name:     "No status",
+			builder:  cmv1.NewNodePool().Replicas(3),
+			expected: arm.ProvisioningStateAccepted,
+		},
+		{
+			name: "Scaling up",
+			builder: cmv1.NewNodePool().Replicas(3).
+				Status(cmv1.NewNodePoolStatus().CurrentReplicas(1)),
+			expected: arm.ProvisioningStateProvisioning,
+		},
+		{
+			name: "Replicas satisfied",
+			builder: cmv1.NewNodePool().Replicas(3).
+				Status(cmv1.NewNodePoolStatus().CurrentReplicas(3)),
+			expected: arm.ProvisioningStateSucceeded,
+		},
+		{
+			name: "Autoscaling within range",
+			builder: cmv1.NewNodePool().
+				Autoscaling(cmv1.NewNodePoolAutoscaling().MinReplica(1).MaxReplica(5)).
+				Status(cmv1.NewNodePoolStatus().CurrentReplicas(2)),
+			expected: arm.ProvisioningStateSucceeded,
+		},
+		{
+			name: "Autoscaling below range",
+			builder: cmv1.NewNodePool().
+				Autoscaling(cmv1.NewNodePoolAutoscaling().MinReplica(2).MaxReplica(5)).
+				Status(cmv1.NewNodePoolStatus().CurrentReplicas(0)),
+			expected: arm.ProvisioningStateProvisioning,
+		},
+		{
+			name: "Scaling up failed",
+			builder: cmv1.NewNodePool().Replicas(3).
+				Status(cmv1.NewNodePoolStatus().CurrentReplicas(1).Message("insufficient quota")),
+			expected: arm.ProvisioningStateFailed,
+		},
+		{
+			name: "Failed before any replica",
+			builder: cmv1.NewNodePool().Replicas(3).
+				Status(cmv1.NewNodePoolStatus().Message("invalid subnet")),
+			expected: arm.ProvisioningStateFailed,
+		},
+		{
+			name: "Replicas satisfied with a message",
+			builder: cmv1.NewNodePool().Replicas(3).
+				Status(cmv1.NewNodePoolStatus().CurrentReplicas(3).Message("upgrade pending")),
+			expected: arm.ProvisioningStateSucceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			np, err := test.builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := ConvertCSNodePoolStatusToProvisioningState(np)
+			if actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCSErrorToCloudError(t *testing.T) {
+	const resourceID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatusCode int
+		expectedCode       string
+		expectedTarget     string
+	}{
+		{
+			name:               "Unknown version",
+			err:                newOCMError(t, http.StatusBadRequest, "Version 'openshift-v4.99.0' is not supported"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       arm.CloudErrorCodeInvalidParameter,
+			expectedTarget:     "properties.spec.version.id",
+		},
+		{
+			name:               "Invalid attribute",
+			err:                newOCMError(t, http.StatusBadRequest, "Attribute 'network.pod_cidr' must be a valid CIDR"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       arm.CloudErrorCodeInvalidParameter,
+			expectedTarget:     "properties.spec.network.podCidr",
+		},
+		{
+			name:               "Unrecognized bad request",
+			err:                newOCMError(t, http.StatusBadRequest, "Something is wrong"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       arm.CloudErrorCodeInvalidRequestContent,
+		},
+		{
+			name:               "Not found",
+			err:                fmt.Errorf("wrapped: %w", newOCMError(t, http.StatusNotFound, "Cluster 'abc' not found")),
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       arm.CloudErrorCodeNotFound,
+			expectedTarget:     resourceID,
+		},
+		{
+			name:               "Conflict",
+			err:                newOCMError(t, http.StatusConflict, "Cluster 'mycluster' already exists"),
+			expectedStatusCode: http.StatusConflict,
+			expectedCode:       arm.CloudErrorCodeConflict,
+			expectedTarget:     resourceID,
+		},
+		{
+			name:               "Throttled",
+			err:                newOCMError(t, http.StatusTooManyRequests, "Rate limit exceeded"),
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedCode:       arm.CloudErrorCodeTooManyRequests,
+		},
+		{
+			name:               "Unavailable",
+			err:                newOCMError(t, http.StatusServiceUnavailable, "Service unavailable"),
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedCode:       arm.CloudErrorCodeServiceUnavailable,
+		},
+		{
+			name:               "Forbidden",
+			err:                newOCMError(t, http.StatusForbidden, "Access denied"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       arm.CloudErrorCodeInternalServerError,
+		},
+		{
+			name:               "Not a Cluster Service error",
+			err:                errors.New("connection refused"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       arm.CloudErrorCodeInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloudError := CSErrorToCloudError(test.err, resourceID)
+			if cloudError.StatusCode != test.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", test.expectedStatusCode, cloudError.StatusCode)
+			}
+			if cloudError.Code != test.expectedCode {
+				t.Errorf("expected code %q, got %q", test.expectedCode, cloudError.Code)
+			}
+			if cloudError.Target != test.expectedTarget {
+				t.Errorf("expected target %q, got %q", test.expectedTarget, cloudError.Target)
+			}
+		})
+	}
+}
+
+func newOCMError(t *testing.T, status int, reason string) error {
+	t.Helper()
+
+	err, buildErr := ocmerrors.NewError().Status(status).Reason(reason).Build()
+	if buildErr != nil {
+		t.Fatal(buildErr)
+	}
+	return err
+}
+
+func TestGetCSClusters(t *testing.T) {
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	conn, err := cs.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	f := &Frontend{clusterServiceClient: ocm.NewClusterServiceClient(conn)}
+
+	// Request more clusters than fit in one batch, but not all of them.
+	var requested []string
+	for i := 0; i < csListPageSize+50; i++ {
+		cluster, err := cmv1.NewCluster().Name(fmt.Sprintf("cluster%d", i)).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := cs.AddCluster(cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%5 != 0 {
+			requested = append(requested, id)
+		}
+	}
+	requested = append(requested, "missing", "x' or id != '")
+
+	clusters, err := f.getCSClusters(context.Background(), requested)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := len(requested) - 2
+	if len(clusters) != expected {
+		t.Errorf("expected %d clusters, got %d", expected, len(clusters))
+	}
+	for _, id := range requested[:expected] {
+		if clusters[id] == nil {
+			t.Errorf("expected cluster %s", id)
+		}
+	}
+}
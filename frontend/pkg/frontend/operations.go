@@ -0,0 +1,228 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// AddAsyncOperationHeaders adds the response headers that ARM and its
+// clients use to poll an asynchronous operation: Azure-AsyncOperation
+// points to the operation status endpoint and Location points to the
+// operation result endpoint.
+// See https://github.com/cloud-and-ai-microsoft/resource-provider-contract/blob/master/v1.0/async-api-reference.md
+func (f *Frontend) AddAsyncOperationHeaders(writer http.ResponseWriter, request *http.Request, doc *database.OperationDocument) {
+	writer.Header().Set(arm.HeaderNameAsyncOperation, f.operationURL(request, api.OperationStatusResourceTypeName, doc))
+	writer.Header().Set(arm.HeaderNameLocation, f.operationURL(request, api.OperationResultResourceTypeName, doc))
+}
+
+// operationURL returns an absolute URL for the given location-scoped
+// operation resource type. The scheme and host are taken from the
+// Referer header, which ARM sets to the original request URL, and
+// fall back to the request's own host.
+func (f *Frontend) operationURL(request *http.Request, resourceTypeName string, doc *database.OperationDocument) string {
+	u := &url.URL{
+		Scheme: "https",
+		Host:   request.Host,
+		Path: path.Join(
+			"/subscriptions", doc.PartitionKey,
+			"providers", api.ProviderNamespace,
+			"locations", f.region,
+			resourceTypeName, doc.ID),
+	}
+
+	if referer, err := url.Parse(request.Referer()); err == nil && referer.IsAbs() {
+		u.Scheme = referer.Scheme
+		u.Host = referer.Host
+	}
+
+	if apiVersion := request.URL.Query().Get(APIVersionKey); apiVersion != "" {
+		u.RawQuery = url.Values{APIVersionKey: []string{apiVersion}}.Encode()
+	}
+
+	return u.String()
+}
+
+// OperationStatus serves the Azure-AsyncOperation endpoint for an
+// asynchronous operation.
+func (f *Frontend) OperationStatus(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	// Use the original, non-lowercased path as the operation status ID.
+	operationStatusID, _ := OriginalPathFromContext(ctx)
+	operationID := request.PathValue(PathSegmentOperationID)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	doc, err := f.dbClient.GetOperationDoc(ctx, operationID, subscriptionID)
+	if err != nil {
+		f.writeOperationLookupError(writer, request, operationID, err)
+		return
+	}
+
+	resp, err := json.Marshal(doc.ToStatus(operationStatusID))
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
+
+// OperationResult serves the Location endpoint for an asynchronous
+// operation. Until the operation reaches a terminal state the response
+// is 202 Accepted. Afterward the response is exactly as though the
+// original request had completed synchronously.
+func (f *Frontend) OperationResult(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	operationID := request.PathValue(PathSegmentOperationID)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+
+	doc, err := f.dbClient.GetOperationDoc(ctx, operationID, subscriptionID)
+	if err != nil {
+		f.writeOperationLookupError(writer, request, operationID, err)
+		return
+	}
+
+	switch doc.Status {
+	case arm.ProvisioningStateSucceeded:
+		// Handled below.
+	case arm.ProvisioningStateFailed, arm.ProvisioningStateCanceled:
+		cloudError := &arm.CloudError{
+			StatusCode:     http.StatusInternalServerError,
+			CloudErrorBody: doc.Error,
+		}
+		if cloudError.CloudErrorBody == nil {
+			cloudError.CloudErrorBody = &arm.CloudErrorBody{
+				Code:    arm.CloudErrorCodeInternalServerError,
+				Message: fmt.Sprintf("Operation %s.", doc.Status),
+			}
+		}
+		arm.WriteCloudError(writer, cloudError)
+		return
+	default:
+		f.AddAsyncOperationHeaders(writer, request, doc)
+		writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var successStatusCode int
+	switch doc.Request {
+	case database.OperationRequestDelete:
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	case database.OperationRequestCreate:
+		successStatusCode = http.StatusCreated
+	default:
+		successStatusCode = http.StatusOK
+	}
+
+	var resp []byte
+	if IsNodePoolResourceID(doc.ExternalID) {
+		resp, err = f.marshalNodePool(ctx, versionedInterface, doc.ExternalID, subscriptionID)
+	} else {
+		resp, err = f.marshalCluster(ctx, versionedInterface, doc.ExternalID, subscriptionID)
+	}
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(successStatusCode)
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
+
+// IsNodePoolResourceID returns true if the resource ID refers to a node pool.
+func IsNodePoolResourceID(resourceID string) bool {
+	parsed, err := azcorearm.ParseResourceID(resourceID)
+	return err == nil && strings.EqualFold(parsed.ResourceType.String(), api.NodePoolResourceType)
+}
+
+// marshalCluster returns the versioned JSON representation of a cluster.
+func (f *Frontend) marshalCluster(ctx context.Context, versionedInterface api.Version, resourceID, subscriptionID string) ([]byte, error) {
+	clusterDoc, err := f.dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document for %s: %w", resourceID, err)
+	}
+
+	csCluster, err := f.clusterServiceClient.GetCSCluster(ctx, clusterDoc.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cluster %s from clusters-service: %w", clusterDoc.ClusterID, err)
+	}
+
+	hcpCluster, err := f.clusterFromDocument(clusterDoc, csCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(versionedInterface.NewHCPOpenShiftCluster(hcpCluster))
+}
+
+// marshalNodePool returns the versioned JSON representation of a node pool.
+func (f *Frontend) marshalNodePool(ctx context.Context, versionedInterface api.Version, resourceID, subscriptionID string) ([]byte, error) {
+	clusterResourceID := path.Dir(path.Dir(resourceID))
+	clusterDoc, err := f.dbClient.GetClusterDoc(ctx, clusterResourceID, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document for %s: %w", clusterResourceID, err)
+	}
+
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, resourceID, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document for %s: %w", resourceID, err)
+	}
+
+	hcpNodePool, err := f.getNodePool(ctx, clusterDoc, nodePoolDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(versionedInterface.NewHCPOpenShiftClusterNodePool(hcpNodePool))
+}
+
+// writeOperationLookupError writes an appropriate response for a failed
+// operation document lookup.
+func (f *Frontend) writeOperationLookupError(writer http.ResponseWriter, request *http.Request, operationID string, err error) {
+	if errors.Is(err, database.ErrNotFound) {
+		originalPath, _ := OriginalPathFromContext(request.Context())
+		f.logger.Error(fmt.Sprintf("operation document not found: %s", operationID))
+		arm.WriteError(
+			writer, http.StatusNotFound,
+			arm.CloudErrorCodeNotFound, originalPath,
+			"The operation '%s' could not be found.", operationID)
+	} else {
+		f.logger.Error(fmt.Sprintf("failed to fetch operation document %s: %v", operationID, err))
+		arm.WriteInternalServerError(writer)
+	}
+}
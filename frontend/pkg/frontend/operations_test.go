@@ -0,0 +1,148 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestAddAsyncOperationHeaders(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	doc := database.NewOperationDocument(database.OperationRequestCreate, subscriptionID, "/resource/id", "internal-id")
+
+	tests := []struct {
+		name             string
+		referer          string
+		expectedLocation string
+		expectedStatus   string
+	}{
+		{
+			name:             "No referer - uses request host",
+			expectedStatus:   "https://frontend.example.com/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOperationsStatus/" + doc.ID + "?api-version=2024-06-10-preview",
+			expectedLocation: "https://frontend.example.com/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOperationResults/" + doc.ID + "?api-version=2024-06-10-preview",
+		},
+		{
+			name:             "Referer - uses referer host",
+			referer:          "https://management.azure.com/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster?api-version=2024-06-10-preview",
+			expectedStatus:   "https://management.azure.com/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOperationsStatus/" + doc.ID + "?api-version=2024-06-10-preview",
+			expectedLocation: "https://management.azure.com/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOperationResults/" + doc.ID + "?api-version=2024-06-10-preview",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &Frontend{region: "eastus"}
+
+			request := httptest.NewRequest(http.MethodPut, "https://frontend.example.com/some/path?api-version=2024-06-10-preview", nil)
+			if test.referer != "" {
+				request.Header.Set("Referer", test.referer)
+			}
+			writer := httptest.NewRecorder()
+
+			f.AddAsyncOperationHeaders(writer, request, doc)
+
+			if got := writer.Header().Get(arm.HeaderNameAsyncOperation); got != test.expectedStatus {
+				t.Errorf("expected %s header %q, got %q", arm.HeaderNameAsyncOperation, test.expectedStatus, got)
+			}
+			if got := writer.Header().Get(arm.HeaderNameLocation); got != test.expectedLocation {
+				t.Errorf("expected %s header %q, got %q", arm.HeaderNameLocation, test.expectedLocation, got)
+			}
+		})
+	}
+}
+
+func TestOperationStatus(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	tests := []struct {
+		name               string
+		status             arm.ProvisioningState
+		missing            bool
+		expectedStatusCode int
+		expectEndTime      bool
+	}{
+		{
+			name:               "Operation in progress",
+			status:             arm.ProvisioningStateAccepted,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Operation succeeded",
+			status:             arm.ProvisioningStateSucceeded,
+			expectedStatusCode: http.StatusOK,
+			expectEndTime:      true,
+		},
+		{
+			name:               "Operation not found",
+			missing:            true,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &Frontend{
+				dbClient: database.NewCache(),
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				metrics:  NewPrometheusEmitter(),
+				region:   "eastus",
+			}
+
+			err := f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+				PartitionKey: subscriptionID,
+				Subscription: &arm.Subscription{State: arm.Registered},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc := database.NewOperationDocument(database.OperationRequestCreate, subscriptionID, "/resource/id", "internal-id")
+			doc.Status = test.status
+			if !test.missing {
+				err = f.dbClient.CreateOperationDoc(context.TODO(), doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ts := newTestServer(t, f)
+
+			rs, err := ts.Client().Get(ts.URL + "/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOperationsStatus/" + doc.ID + "?api-version=2024-06-10-preview")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rs.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected status code %d, got %d", test.expectedStatusCode, rs.StatusCode)
+			}
+
+			if rs.StatusCode != http.StatusOK {
+				return
+			}
+
+			var status arm.OperationStatus
+			err = json.NewDecoder(rs.Body).Decode(&status)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if status.Name != doc.ID {
+				t.Errorf("expected name %q, got %q", doc.ID, status.Name)
+			}
+			if status.Status != test.status {
+				t.Errorf("expected status %q, got %q", test.status, status.Status)
+			}
+			if (status.EndTime != nil) != test.expectEndTime {
+				t.Errorf("expected end time present to be %t, got %v", test.expectEndTime, status.EndTime)
+			}
+		})
+	}
+}
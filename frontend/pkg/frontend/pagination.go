@@ -0,0 +1,93 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// maxPageSize is the largest number of items returned in one page of a
+// list, regardless of the page size requested.
+const maxPageSize = 100
+
+// skipToken is the decoded form of the opaque $skipToken parameter that
+// continues a paginated list. Each list sets whichever field matches
+// its backing store.
+type skipToken struct {
+	// Continuation resumes a database query
+	Continuation string `json:"c,omitempty"`
+	// Page is the next Cluster Service page number
+	Page int `json:"p,omitempty"`
+}
+
+// encode returns the skip token in its opaque wire format.
+func (t skipToken) encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseListParameters returns the page size and skip token requested for
+// a paginated list. The page size defaults to and is capped at
+// maxPageSize.
+func parseListParameters(request *http.Request) (int, skipToken, *arm.CloudError) {
+	var token skipToken
+	pageSize := maxPageSize
+	query := request.URL.Query()
+
+	if value := query.Get(TopKey); value != "" {
+		top, err := strconv.Atoi(value)
+		if err != nil || top < 1 {
+			return 0, token, arm.NewCloudError(
+				http.StatusBadRequest, arm.CloudErrorCodeInvalidParameter, TopKey,
+				"The value '%s' of the '%s' parameter must be a positive integer.", value, TopKey)
+		}
+		pageSize = min(top, maxPageSize)
+	}
+
+	if value := query.Get(SkipTokenKey); value != "" {
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err == nil {
+			err = json.Unmarshal(data, &token)
+		}
+		if err != nil || token.Page < 0 {
+			return 0, token, arm.NewCloudError(
+				http.StatusBadRequest, arm.CloudErrorCodeInvalidParameter, SkipTokenKey,
+				"The value of the '%s' parameter is not valid.", SkipTokenKey)
+		}
+	}
+
+	return pageSize, token, nil
+}
+
+// nextLink returns the absolute URL of the next page of a list. It is
+// the URL of the request as ARM received it, which ARM passes in the
+// Referer header, with the skip token replaced. Without a usable Referer
+// the URL is rebuilt from the request itself.
+func nextLink(request *http.Request, token skipToken) string {
+	u, err := url.Parse(request.Referer())
+	if err != nil || !u.IsAbs() {
+		originalPath, _ := OriginalPathFromContext(request.Context())
+		if originalPath == "" {
+			originalPath = request.URL.Path
+		}
+		u = &url.URL{
+			Scheme:   "https",
+			Host:     request.Host,
+			Path:     originalPath,
+			RawQuery: request.URL.RawQuery,
+		}
+	}
+
+	query := u.Query()
+	query.Set(SkipTokenKey, token.encode())
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
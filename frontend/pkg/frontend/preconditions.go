@@ -0,0 +1,81 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// CheckPreconditions evaluates the If-Match and If-None-Match request
+// headers against the current state of a resource. exists indicates
+// whether the resource exists and etag is its current entity tag.
+// A 412 Precondition Failed error is returned if either condition is
+// not satisfied.
+// See https://www.rfc-editor.org/rfc/rfc9110#section-13.1
+func CheckPreconditions(request *http.Request, exists bool, etag string) *arm.CloudError {
+	originalPath, _ := OriginalPathFromContext(request.Context())
+
+	if ifMatch := request.Header.Get(arm.HeaderNameIfMatch); ifMatch != "" {
+		if !exists || !etagListContains(ifMatch, etag) {
+			return arm.NewCloudError(
+				http.StatusPreconditionFailed,
+				arm.CloudErrorCodePreconditionFailed, originalPath,
+				"The condition specified in the %s header was not satisfied.",
+				arm.HeaderNameIfMatch)
+		}
+	}
+
+	if ifNoneMatch := request.Header.Get(arm.HeaderNameIfNoneMatch); ifNoneMatch != "" {
+		if exists && etagListContains(ifNoneMatch, etag) {
+			return arm.NewCloudError(
+				http.StatusPreconditionFailed,
+				arm.CloudErrorCodePreconditionFailed, originalPath,
+				"The condition specified in the %s header was not satisfied.",
+				arm.HeaderNameIfNoneMatch)
+		}
+	}
+
+	return nil
+}
+
+// etagListContains returns true if a comma-separated list of entity tags
+// from a conditional request header matches etag. The wildcard "*" matches
+// any entity tag. Weak entity tags are compared as strong tags since the
+// document store only issues strong entity tags.
+func etagListContains(list, etag string) bool {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" {
+			return true
+		}
+		item = strings.TrimPrefix(item, "W/")
+		if strings.Trim(item, `"`) == strings.Trim(etag, `"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDocumentWriteError writes an appropriate response for a failed
+// resource document write. A conditional write that lost a race with
+// another request results in 409 Conflict.
+func (f *Frontend) writeDocumentWriteError(writer http.ResponseWriter, request *http.Request, err error) {
+	var conflictError *database.ConflictError
+	if errors.As(err, &conflictError) {
+		originalPath, _ := OriginalPathFromContext(request.Context())
+		f.logger.Error(err.Error())
+		arm.WriteError(
+			writer, http.StatusConflict,
+			arm.CloudErrorCodeConflict, originalPath,
+			"The resource was modified by another request. Retry the request.")
+	} else {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+	}
+}
@@ -0,0 +1,181 @@
+package frontend
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	const etag = `"00000000-0000-0000-0000-000000000000"`
+
+	tests := []struct {
+		name        string
+		ifMatch     string
+		ifNoneMatch string
+		exists      bool
+		expectError bool
+	}{
+		{
+			name:   "No conditions",
+			exists: true,
+		},
+		{
+			name:    "If-Match matches",
+			ifMatch: etag,
+			exists:  true,
+		},
+		{
+			name:    "If-Match matches one of several",
+			ifMatch: `"other", ` + etag,
+			exists:  true,
+		},
+		{
+			name:    "If-Match matches weak tag",
+			ifMatch: "W/" + etag,
+			exists:  true,
+		},
+		{
+			name:        "If-Match does not match",
+			ifMatch:     `"other"`,
+			exists:      true,
+			expectError: true,
+		},
+		{
+			name:    "If-Match wildcard with existing resource",
+			ifMatch: "*",
+			exists:  true,
+		},
+		{
+			name:        "If-Match wildcard with missing resource",
+			ifMatch:     "*",
+			expectError: true,
+		},
+		{
+			name:        "If-None-Match wildcard with existing resource",
+			ifNoneMatch: "*",
+			exists:      true,
+			expectError: true,
+		},
+		{
+			name:        "If-None-Match wildcard with missing resource",
+			ifNoneMatch: "*",
+		},
+		{
+			name:        "If-None-Match matches",
+			ifNoneMatch: etag,
+			exists:      true,
+			expectError: true,
+		},
+		{
+			name:        "If-None-Match does not match",
+			ifNoneMatch: `"other"`,
+			exists:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/some/path", nil)
+			if test.ifMatch != "" {
+				request.Header.Set(arm.HeaderNameIfMatch, test.ifMatch)
+			}
+			if test.ifNoneMatch != "" {
+				request.Header.Set(arm.HeaderNameIfNoneMatch, test.ifNoneMatch)
+			}
+
+			var currentETag string
+			if test.exists {
+				currentETag = etag
+			}
+
+			cloudError := CheckPreconditions(request, test.exists, currentETag)
+			if test.expectError {
+				if cloudError == nil {
+					t.Fatal("expected an error")
+				}
+				if cloudError.StatusCode != http.StatusPreconditionFailed {
+					t.Errorf("expected status code %d, got %d", http.StatusPreconditionFailed, cloudError.StatusCode)
+				}
+			} else if cloudError != nil {
+				t.Errorf("unexpected error: %v", cloudError)
+			}
+		})
+	}
+}
+
+func TestClusterDeletePreconditionFailed(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const clusterPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+
+	tests := []struct {
+		name       string
+		clusterDoc bool
+	}{
+		{
+			name:       "Existing cluster with stale ETag",
+			clusterDoc: true,
+		},
+		{
+			name:       "Missing cluster",
+			clusterDoc: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &Frontend{
+				dbClient: database.NewCache(),
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				metrics:  NewPrometheusEmitter(),
+				region:   "eastus",
+			}
+
+			err := f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+				PartitionKey: subscriptionID,
+				Subscription: &arm.Subscription{State: arm.Registered},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.clusterDoc {
+				err = f.dbClient.SetClusterDoc(context.TODO(), &database.HCPOpenShiftClusterDocument{
+					Key:          strings.ToLower(clusterPath),
+					PartitionKey: subscriptionID,
+					ClusterID:    "cluster-id",
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ts := newTestServer(t, f)
+
+			req, err := http.NewRequest(http.MethodDelete, ts.URL+clusterPath+"?api-version=2024-06-10-preview", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(arm.HeaderNameIfMatch, `"stale"`)
+
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rs.StatusCode != http.StatusPreconditionFailed {
+				t.Errorf("expected status code %d, got %d", http.StatusPreconditionFailed, rs.StatusCode)
+			}
+			if code := rs.Header.Get(arm.HeaderNameErrorCode); code != arm.CloudErrorCodePreconditionFailed {
+				t.Errorf("expected error code %q, got %q", arm.CloudErrorCodePreconditionFailed, code)
+			}
+		})
+	}
+}
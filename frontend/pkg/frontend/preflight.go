@@ -0,0 +1,533 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"path"
+	"strings"
+
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const subnetResourceType = "Microsoft.Network/virtualNetworks/subnets"
+
+// deploymentTemplateFields are resource fields in a deployment preflight
+// request that belong to the template rather than the resource, and which
+// the versioned API types would reject as unknown.
+var deploymentTemplateFields = []string{
+	"apiVersion",
+	"comments",
+	"condition",
+	"copy",
+	"dependsOn",
+	"scope",
+}
+
+// deploymentResourceBody returns a resource from a deployment preflight
+// request as a resource request body.
+func deploymentResourceBody(raw json.RawMessage) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for _, field := range deploymentTemplateFields {
+		delete(fields, field)
+	}
+	return json.Marshal(fields)
+}
+
+// deploymentResource is a resource in a deployment preflight request,
+// kept alongside its raw JSON so other resources in the deployment can
+// refer to it.
+type deploymentResource struct {
+	*arm.DeploymentPreflightResource
+	raw json.RawMessage
+}
+
+// newDeploymentCluster statically validates a cluster resource in a
+// deployment preflight request as if for a cluster creation request,
+// and returns it normalized. An error means the resource could not be
+// parsed at all.
+func newDeploymentCluster(resource deploymentResource, features api.FeatureSet) (*api.HCPOpenShiftCluster, *arm.CloudError, error) {
+	versionedInterface, ok := api.Lookup(resource.APIVersion)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported API version '%s'", resource.APIVersion)
+	}
+	versionedCluster := versionedInterface.NewHCPOpenShiftCluster(nil)
+
+	body, err := deploymentResourceBody(resource.raw)
+	if err == nil {
+		err = json.Unmarshal(body, versionedCluster)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cloudError := versionedCluster.ValidateStatic(versionedCluster, false, http.MethodPut, features); cloudError != nil {
+		return nil, cloudError, nil
+	}
+
+	hcpCluster := api.NewDefaultHCPOpenShiftCluster()
+	versionedCluster.Normalize(hcpCluster)
+	return hcpCluster, nil, nil
+}
+
+// newDeploymentNodePool is like newDeploymentCluster for node pools.
+func newDeploymentNodePool(resource deploymentResource) (*api.HCPOpenShiftClusterNodePool, *arm.CloudError, error) {
+	versionedInterface, ok := api.Lookup(resource.APIVersion)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported API version '%s'", resource.APIVersion)
+	}
+	versionedNodePool := versionedInterface.NewHCPOpenShiftClusterNodePool(nil)
+
+	body, err := deploymentResourceBody(resource.raw)
+	if err == nil {
+		err = json.Unmarshal(body, versionedNodePool)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cloudError := versionedNodePool.ValidateStatic(versionedNodePool, false, http.MethodPut); cloudError != nil {
+		return nil, cloudError, nil
+	}
+
+	hcpNodePool := api.NewDefaultHCPOpenShiftClusterNodepool()
+	versionedNodePool.Normalize(hcpNodePool)
+	return hcpNodePool, nil, nil
+}
+
+// staticValidationError wraps the static validation error for a resource
+// in a deployment preflight request.
+func staticValidationError(resource deploymentResource, resourceID string, cloudError *arm.CloudError) arm.CloudErrorBody {
+	var details []arm.CloudErrorBody
+
+	// This avoids double-nesting details when there's multiple errors.
+	//
+	// To illustrate, instead of:
+	//
+	// {
+	//   "code": "MultipleErrorsOccurred"
+	//   "message": "Content validation failed for {{RESOURCE_NAME}}"
+	//   "target": "{{RESOURCE_ID}}"
+	//   "details": [
+	//     {
+	//       "code": "MultipleErrorsOccurred"
+	//       "message": "Content validation failed on multiple fields"
+	//       "details": [
+	//         ...field-specific validation errors...
+	//       ]
+	//     }
+	//   ]
+	// }
+	//
+	// we want:
+	//
+	// {
+	//   "code": "MultipleErrorsOccurred"
+	//   "message": "Content validation failed for {{RESOURCE_NAME}}"
+	//   "target": "{{RESOURCE_ID}}"
+	//   "details": [
+	//     ...field-specific validation errors...
+	//   ]
+	// }
+	//
+	if len(cloudError.CloudErrorBody.Details) > 0 {
+		details = cloudError.CloudErrorBody.Details
+	} else {
+		details = []arm.CloudErrorBody{*cloudError.CloudErrorBody}
+	}
+
+	return arm.CloudErrorBody{
+		Code:    cloudError.Code,
+		Message: fmt.Sprintf("Content validation failed for '%s'", resource.Name),
+		Target:  resourceID,
+		Details: details,
+	}
+}
+
+// preflightError wraps the semantic check failures for a resource in a
+// deployment preflight request, or returns nil if there are none.
+func preflightError(resource deploymentResource, resourceID string, details []arm.CloudErrorBody) *arm.CloudErrorBody {
+	if len(details) == 0 {
+		return nil
+	}
+
+	code := arm.CloudErrorCodeMultipleErrorsOccurred
+	if len(details) == 1 {
+		code = details[0].Code
+	}
+
+	return &arm.CloudErrorBody{
+		Code:    code,
+		Message: fmt.Sprintf("Preflight validation failed for '%s'", resource.Name),
+		Target:  resourceID,
+		Details: details,
+	}
+}
+
+// preflightClusterResource validates a cluster resource in a deployment
+// preflight request and returns an error describing any failures.
+// deploymentResources maps the lowercased ID of each resource in the
+// deployment to the resource, so references between them can be
+// resolved.
+func (f *Frontend) preflightClusterResource(ctx context.Context, resource deploymentResource, resourceID string, deploymentResources map[string]deploymentResource) *arm.CloudErrorBody {
+	hcpCluster, cloudError, err := newDeploymentCluster(resource, FeaturesFromContext(ctx))
+	if err != nil {
+		// Preflight is best effort: failure to parse a resource is not a validation failure.
+		f.logger.Warn(fmt.Sprintf("Failed to unmarshal %s resource named '%s': %s", resource.Type, resource.Name, err))
+		return nil
+	}
+	if cloudError != nil {
+		errorBody := staticValidationError(resource, resourceID, cloudError)
+		return &errorBody
+	}
+
+	return preflightError(resource, resourceID, f.preflightCluster(ctx, hcpCluster, resourceID, deploymentResources))
+}
+
+// preflightNodePoolResource is like preflightClusterResource for node pools.
+func (f *Frontend) preflightNodePoolResource(ctx context.Context, resource deploymentResource, resourceID string, deploymentResources map[string]deploymentResource) *arm.CloudErrorBody {
+	hcpNodePool, cloudError, err := newDeploymentNodePool(resource)
+	if err != nil {
+		// Preflight is best effort: failure to parse a resource is not a validation failure.
+		f.logger.Warn(fmt.Sprintf("Failed to unmarshal %s resource named '%s': %s", resource.Type, resource.Name, err))
+		return nil
+	}
+	if cloudError != nil {
+		errorBody := staticValidationError(resource, resourceID, cloudError)
+		return &errorBody
+	}
+	hcpNodePool.Location = resource.Location
+
+	return preflightError(resource, resourceID, f.preflightNodePool(ctx, hcpNodePool, resourceID, deploymentResources))
+}
+
+// preflightCluster performs the semantic checks on a cluster in a
+// deployment preflight request that static validation cannot. The checks
+// consult Cluster Service and the database but never Azure itself.
+//
+// Preflight is best-effort: a check that cannot be completed is skipped
+// rather than reported as a failure.
+func (f *Frontend) preflightCluster(ctx context.Context, cluster *api.HCPOpenShiftCluster, resourceID string, deploymentResources map[string]deploymentResource) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	parsed, err := azcorearm.ParseResourceID(resourceID)
+	if err != nil {
+		f.logger.Warn(fmt.Sprintf("Skipping preflight checks for '%s': %v", resourceID, err))
+		return nil
+	}
+
+	errorDetails = append(errorDetails, checkCIDROverlap(&cluster.Properties.Spec.Network)...)
+	errorDetails = append(errorDetails, checkSubnet(cluster, parsed.SubscriptionID, deploymentResources)...)
+	errorDetails = append(errorDetails, f.checkVersionExists(ctx, &cluster.Properties.Spec.Version)...)
+	errorDetails = append(errorDetails, f.checkClusterConflicts(ctx, cluster, resourceID, parsed)...)
+
+	return errorDetails
+}
+
+// preflightNodePool performs the semantic checks on a node pool in a
+// deployment preflight request. The node pool is checked against its
+// parent cluster, which is taken from the same deployment if declared
+// there so a deployment creating both is checked together.
+func (f *Frontend) preflightNodePool(ctx context.Context, nodePool *api.HCPOpenShiftClusterNodePool, resourceID string, deploymentResources map[string]deploymentResource) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	parsed, err := azcorearm.ParseResourceID(resourceID)
+	if err != nil || !strings.EqualFold(parsed.ResourceType.String(), api.NodePoolResourceType) {
+		return []arm.CloudErrorBody{{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Invalid node pool name '%s' (must be of the form '{clusterName}/{nodePoolName}')", path.Base(resourceID)),
+			Target:  "name",
+		}}
+	}
+
+	errorDetails = append(errorDetails, f.checkVersionExists(ctx, &nodePool.Properties.Spec.Version)...)
+
+	// Deploying an existing node pool again updates it, which only
+	// conflicts while the node pool is being deleted.
+	nodePoolDoc, err := f.dbClient.GetNodePoolDoc(ctx, strings.ToLower(resourceID), parsed.SubscriptionID)
+	switch {
+	case err == nil && nodePoolDoc.ProvisioningState == arm.ProvisioningStateDeleting:
+		errorDetails = append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeConflict,
+			Message: fmt.Sprintf("Node pool '%s' in cluster '%s' is being deleted", parsed.Name, parsed.Parent.Name),
+		})
+	case err != nil && !errors.Is(err, database.ErrNotFound):
+		f.logger.Warn(fmt.Sprintf("Skipping preflight conflict check for '%s': %v", resourceID, err))
+	}
+
+	clusterID := parsed.Parent.String()
+	cluster, err := f.getPreflightParentCluster(ctx, clusterID, parsed.SubscriptionID, deploymentResources)
+	if errors.Is(err, database.ErrNotFound) {
+		return append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeResourceNotFound,
+			Message: fmt.Sprintf("Cluster '%s' is neither part of the deployment nor an existing resource in resource group '%s'", parsed.Parent.Name, parsed.ResourceGroupName),
+		})
+	} else if err != nil {
+		f.logger.Warn(fmt.Sprintf("Skipping preflight parent cluster checks for '%s': %v", resourceID, err))
+		return errorDetails
+	}
+
+	errorDetails = append(errorDetails, checkNodePoolMatchesCluster(nodePool, cluster, parsed.Parent.Name)...)
+
+	return errorDetails
+}
+
+// getPreflightParentCluster returns the parent cluster of a node pool in
+// a deployment preflight request, preferring a cluster declared in the
+// same deployment over an existing one.
+func (f *Frontend) getPreflightParentCluster(ctx context.Context, clusterID, subscriptionID string, deploymentResources map[string]deploymentResource) (*api.HCPOpenShiftCluster, error) {
+	if resource, ok := deploymentResources[strings.ToLower(clusterID)]; ok {
+		cluster, cloudError, err := newDeploymentCluster(resource, FeaturesFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if cloudError != nil {
+			// The cluster's own preflight reports the validation failure.
+			return nil, fmt.Errorf("cluster failed static validation: %w", cloudError)
+		}
+		return cluster, nil
+	}
+
+	doc, err := f.dbClient.GetClusterDoc(ctx, strings.ToLower(clusterID), subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	csCluster, err := f.clusterServiceClient.GetCSCluster(ctx, doc.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	return f.clusterFromDocument(doc, csCluster)
+}
+
+// checkNodePoolMatchesCluster verifies a node pool is compatible with its
+// parent cluster: it must share the cluster's region and channel group,
+// and a node pool subnet must be in the cluster's virtual network.
+func checkNodePoolMatchesCluster(nodePool *api.HCPOpenShiftClusterNodePool, cluster *api.HCPOpenShiftCluster, clusterName string) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	if !strings.EqualFold(nodePool.Location, cluster.Location) {
+		errorDetails = append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Node pool must be in region '%s' of cluster '%s', not '%s'", cluster.Location, clusterName, nodePool.Location),
+			Target:  "location",
+		})
+	}
+
+	channelGroup := nodePool.Properties.Spec.Version.ChannelGroup
+	clusterChannelGroup := cluster.Properties.Spec.Version.ChannelGroup
+	if channelGroup != "" && clusterChannelGroup != "" && channelGroup != clusterChannelGroup {
+		errorDetails = append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Channel group '%s' does not match channel group '%s' of cluster '%s'", channelGroup, clusterChannelGroup, clusterName),
+			Target:  "properties.spec.version.channelGroup",
+		})
+	}
+
+	subnetID := nodePool.Properties.Spec.Platform.SubnetID
+	if subnetID == "" {
+		// The node pool uses the cluster subnet.
+		return errorDetails
+	}
+
+	subnet, err := azcorearm.ParseResourceID(subnetID)
+	if err != nil || !strings.EqualFold(subnet.ResourceType.String(), subnetResourceType) {
+		return append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Invalid value '%s' for field 'subnetId' (must be a resource ID of type '%s')", subnetID, subnetResourceType),
+			Target:  "properties.spec.platform.subnetId",
+		})
+	}
+
+	clusterSubnet, err := azcorearm.ParseResourceID(cluster.Properties.Spec.Platform.SubnetID)
+	if err == nil && !strings.EqualFold(subnet.Parent.String(), clusterSubnet.Parent.String()) {
+		errorDetails = append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Subnet '%s' must be in virtual network '%s' of cluster '%s'", subnetID, clusterSubnet.Parent, clusterName),
+			Target:  "properties.spec.platform.subnetId",
+		})
+	}
+
+	return errorDetails
+}
+
+// checkCIDROverlap returns an error for each pair of overlapping
+// pod, service and machine CIDRs.
+func checkCIDROverlap(network *api.NetworkProfile) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	cidrs := []struct {
+		field string
+		value string
+	}{
+		{"podCidr", network.PodCIDR},
+		{"serviceCidr", network.ServiceCIDR},
+		{"machineCidr", network.MachineCIDR},
+	}
+
+	for i := range cidrs {
+		a, err := netip.ParsePrefix(cidrs[i].value)
+		if err != nil {
+			// Static validation reports malformed CIDRs.
+			continue
+		}
+		for j := i + 1; j < len(cidrs); j++ {
+			b, err := netip.ParsePrefix(cidrs[j].value)
+			if err != nil {
+				continue
+			}
+			if a.Overlaps(b) {
+				errorDetails = append(errorDetails, arm.CloudErrorBody{
+					Code:    arm.CloudErrorCodeInvalidRequestContent,
+					Message: fmt.Sprintf("Field '%s' (%s) overlaps with field '%s' (%s)", cidrs[i].field, a, cidrs[j].field, b),
+					Target:  "properties.spec.network." + cidrs[i].field,
+				})
+			}
+		}
+	}
+
+	return errorDetails
+}
+
+// checkSubnet verifies the cluster subnet is in the deployment's
+// subscription. Its region can only be verified when the subnet's
+// virtual network is part of the same deployment.
+func checkSubnet(cluster *api.HCPOpenShiftCluster, subscriptionID string, deploymentResources map[string]deploymentResource) []arm.CloudErrorBody {
+	const target = "properties.spec.platform.subnetId"
+
+	subnetID := cluster.Properties.Spec.Platform.SubnetID
+	if subnetID == "" {
+		// Static validation reports a missing subnet.
+		return nil
+	}
+
+	subnet, err := azcorearm.ParseResourceID(subnetID)
+	if err != nil || !strings.EqualFold(subnet.ResourceType.String(), subnetResourceType) {
+		return []arm.CloudErrorBody{{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Invalid value '%s' for field 'subnetId' (must be a resource ID of type '%s')", subnetID, subnetResourceType),
+			Target:  target,
+		}}
+	}
+
+	if !strings.EqualFold(subnet.SubscriptionID, subscriptionID) {
+		return []arm.CloudErrorBody{{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Subnet '%s' must be in subscription '%s'", subnetID, subscriptionID),
+			Target:  target,
+		}}
+	}
+
+	vnet, ok := deploymentResources[strings.ToLower(subnet.Parent.String())]
+	if ok && !strings.EqualFold(vnet.Location, cluster.Location) {
+		return []arm.CloudErrorBody{{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Subnet '%s' must be in region '%s', but its virtual network is in '%s'", subnetID, cluster.Location, vnet.Location),
+			Target:  target,
+		}}
+	}
+
+	return nil
+}
+
+// checkVersionExists verifies Cluster Service offers the cluster version.
+func (f *Frontend) checkVersionExists(ctx context.Context, version *api.VersionProfile) []arm.CloudErrorBody {
+	if version.ID == "" {
+		// Static validation reports a missing version.
+		return nil
+	}
+
+	search := csVersionsSearch + " AND id = " + csStringLiteral(version.ID)
+	versions, _, err := f.clusterServiceClient.ListCSVersions(ctx, search, 1, 1)
+	if err != nil {
+		f.logger.Warn(fmt.Sprintf("Skipping preflight version check for '%s': %v", version.ID, err))
+		return nil
+	}
+
+	if len(versions) == 0 {
+		return []arm.CloudErrorBody{{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf("Version '%s' is not available", version.ID),
+			Target:  "properties.spec.version.id",
+		}}
+	}
+
+	return nil
+}
+
+// checkClusterConflicts verifies the cluster does not conflict with an
+// existing one. Deploying an existing cluster again updates it, which only
+// conflicts while the cluster is being deleted. A new cluster must not use
+// the base domain prefix of another cluster in the resource group.
+func (f *Frontend) checkClusterConflicts(ctx context.Context, cluster *api.HCPOpenShiftCluster, resourceID string, parsed *azcorearm.ResourceID) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	key := strings.ToLower(resourceID)
+
+	doc, err := f.dbClient.GetClusterDoc(ctx, key, parsed.SubscriptionID)
+	switch {
+	case err == nil && doc.ProvisioningState == arm.ProvisioningStateDeleting:
+		return []arm.CloudErrorBody{{
+			Code:    arm.CloudErrorCodeConflict,
+			Message: fmt.Sprintf("Cluster '%s' in resource group '%s' is being deleted", parsed.Name, parsed.ResourceGroupName),
+		}}
+	case err == nil:
+		// The base domain prefix cannot be updated.
+		return nil
+	case !errors.Is(err, database.ErrNotFound):
+		f.logger.Warn(fmt.Sprintf("Skipping preflight conflict check for '%s': %v", resourceID, err))
+	}
+
+	prefix := cluster.Properties.Spec.DNS.BaseDomainPrefix
+	if prefix == "" {
+		// Static validation reports a missing prefix.
+		return errorDetails
+	}
+
+	docs, err := f.dbClient.ListClusterDocs(ctx, parsed.SubscriptionID, parsed.ResourceGroupName, "")
+	if err != nil {
+		f.logger.Warn(fmt.Sprintf("Skipping preflight base domain prefix check for '%s': %v", resourceID, err))
+		return errorDetails
+	}
+
+	var clusterIDs []string
+	for _, doc := range docs {
+		if doc.Key != key && doc.ClusterID != "" {
+			clusterIDs = append(clusterIDs, doc.ClusterID)
+		}
+	}
+	if len(clusterIDs) == 0 {
+		return errorDetails
+	}
+
+	csClusters, err := f.getCSClusters(ctx, clusterIDs)
+	if err != nil {
+		f.logger.Warn(fmt.Sprintf("Skipping preflight base domain prefix check for '%s': %v", resourceID, err))
+		return errorDetails
+	}
+
+	for _, csCluster := range csClusters {
+		if strings.EqualFold(csCluster.DomainPrefix(), prefix) {
+			errorDetails = append(errorDetails, arm.CloudErrorBody{
+				Code:    arm.CloudErrorCodeConflict,
+				Message: fmt.Sprintf("Base domain prefix '%s' is already used by cluster '%s' in resource group '%s'", prefix, csCluster.Azure().ResourceName(), parsed.ResourceGroupName),
+				Target:  "properties.spec.dns.baseDomainPrefix",
+			})
+			break
+		}
+	}
+
+	return errorDetails
+}
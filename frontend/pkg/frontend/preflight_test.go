@@ -0,0 +1,300 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/csfake"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// testPreflightCluster returns testClusterBody as a deployment resource.
+func testPreflightCluster(name string) string {
+	return strings.Replace(testClusterBody, "{", `{
+	"name": "`+name+`",
+	"type": "Microsoft.RedHatOpenShift/hcpOpenShiftClusters",
+	"apiVersion": "2024-06-10-preview",`, 1)
+}
+
+// testPreflightNodePool is a deployment resource for a node pool of the
+// cluster returned by testPreflightCluster("myCluster").
+const testPreflightNodePool = `{
+	"name": "myCluster/myNodePool",
+	"type": "Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools",
+	"apiVersion": "2024-06-10-preview",
+	"location": "eastus",
+	"dependsOn": ["[resourceId('Microsoft.RedHatOpenShift/hcpOpenShiftClusters', 'myCluster')]"],
+	"properties": {
+		"spec": {
+			"version": {"id": "openshift-v4.16.0", "channelGroup": "stable"},
+			"platform": {"vmSize": "Standard_D8s_v3"},
+			"replicas": 2
+		}
+	}
+}`
+
+func TestArmDeploymentPreflight(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const resourceGroupPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG"
+	const preflightPath = resourceGroupPath + "/providers/Microsoft.RedHatOpenShift/deployments/myDeployment/preflight"
+	const clustersPath = resourceGroupPath + "/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/"
+
+	tests := []struct {
+		name              string
+		existingClusters  []string
+		existingNodePools []string
+		deletedResources  []string
+		resources         []string
+		failedResource    string
+		expectedTargets   []string
+	}{
+		{
+			name:      "Valid cluster passes",
+			resources: []string{testPreflightCluster("myCluster")},
+		},
+		{
+			name: "Overlapping CIDRs fail",
+			resources: []string{
+				strings.Replace(testPreflightCluster("myCluster"), `"podCidr": "10.128.0.0/14"`, `"podCidr": "10.0.0.0/14"`, 1),
+			},
+			expectedTargets: []string{"properties.spec.network.podCidr"},
+		},
+		{
+			name: "Subnet in another subscription fails",
+			resources: []string{
+				strings.Replace(testPreflightCluster("myCluster"), "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.Network/virtualNetworks", "/subscriptions/11111111-1111-1111-1111-111111111111/resourceGroups/myRG/providers/Microsoft.Network/virtualNetworks", 1),
+			},
+			expectedTargets: []string{"properties.spec.platform.subnetId"},
+		},
+		{
+			name: "Subnet ID of the wrong type fails",
+			resources: []string{
+				strings.Replace(testPreflightCluster("myCluster"), "/subnets/mySubnet", "", 1),
+			},
+			expectedTargets: []string{"properties.spec.platform.subnetId"},
+		},
+		{
+			name: "Virtual network in another region fails",
+			resources: []string{
+				`{"name": "myVNet", "type": "Microsoft.Network/virtualNetworks", "location": "westus", "apiVersion": "2023-09-01"}`,
+				testPreflightCluster("myCluster"),
+			},
+			expectedTargets: []string{"properties.spec.platform.subnetId"},
+		},
+		{
+			name: "Virtual network in the same region passes",
+			resources: []string{
+				`{"name": "myVNet", "type": "Microsoft.Network/virtualNetworks", "location": "eastus", "apiVersion": "2023-09-01"}`,
+				testPreflightCluster("myCluster"),
+			},
+		},
+		{
+			name: "Unknown version fails",
+			resources: []string{
+				strings.Replace(testPreflightCluster("myCluster"), "openshift-v4.16.0", "openshift-v4.99.0", 1),
+			},
+			expectedTargets: []string{"properties.spec.version.id"},
+		},
+		{
+			name:             "Existing cluster passes",
+			existingClusters: []string{"myCluster"},
+			resources:        []string{testPreflightCluster("myCluster")},
+		},
+		{
+			name:             "Cluster being deleted fails",
+			existingClusters: []string{"myCluster"},
+			deletedResources: []string{"myCluster"},
+			resources:        []string{testPreflightCluster("myCluster")},
+			expectedTargets:  []string{""},
+		},
+		{
+			name:             "Base domain prefix used in the resource group fails",
+			existingClusters: []string{"otherCluster"},
+			resources:        []string{testPreflightCluster("myCluster")},
+			expectedTargets:  []string{"properties.spec.dns.baseDomainPrefix"},
+		},
+		{
+			name:      "Node pool with its cluster in the deployment passes",
+			resources: []string{testPreflightCluster("myCluster"), testPreflightNodePool},
+		},
+		{
+			name:             "Node pool of an existing cluster passes",
+			existingClusters: []string{"myCluster"},
+			resources:        []string{testPreflightNodePool},
+		},
+		{
+			name:              "Existing node pool passes",
+			existingClusters:  []string{"myCluster"},
+			existingNodePools: []string{"myNodePool"},
+			resources:         []string{testPreflightNodePool},
+		},
+		{
+			name:              "Node pool being deleted fails",
+			existingClusters:  []string{"myCluster"},
+			existingNodePools: []string{"myNodePool"},
+			deletedResources:  []string{"myCluster/nodePools/myNodePool"},
+			resources:         []string{testPreflightNodePool},
+			failedResource:    "myNodePool",
+			expectedTargets:   []string{""},
+		},
+		{
+			name:             "Node pool in another region than its existing cluster fails",
+			existingClusters: []string{"myCluster"},
+			resources: []string{
+				strings.Replace(testPreflightNodePool, `"location": "eastus"`, `"location": "westus"`, 1),
+			},
+			failedResource:  "myNodePool",
+			expectedTargets: []string{"location"},
+		},
+		{
+			name:            "Node pool without its cluster fails",
+			resources:       []string{testPreflightNodePool},
+			failedResource:  "myNodePool",
+			expectedTargets: []string{""},
+		},
+		{
+			name: "Node pool in another region than its cluster fails",
+			resources: []string{
+				testPreflightCluster("myCluster"),
+				strings.Replace(testPreflightNodePool, `"location": "eastus"`, `"location": "westus"`, 1),
+			},
+			failedResource:  "myNodePool",
+			expectedTargets: []string{"location"},
+		},
+		{
+			name: "Node pool in another channel group than its cluster fails",
+			resources: []string{
+				testPreflightCluster("myCluster"),
+				strings.Replace(testPreflightNodePool, `"channelGroup": "stable"`, `"channelGroup": "candidate"`, 1),
+			},
+			failedResource:  "myNodePool",
+			expectedTargets: []string{"properties.spec.version.channelGroup"},
+		},
+		{
+			name: "Node pool subnet outside the cluster virtual network fails",
+			resources: []string{
+				testPreflightCluster("myCluster"),
+				strings.Replace(testPreflightNodePool, `"vmSize": "Standard_D8s_v3"`, `"vmSize": "Standard_D8s_v3", "subnetId": "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.Network/virtualNetworks/otherVNet/subnets/mySubnet"`, 1),
+			},
+			failedResource:  "myNodePool",
+			expectedTargets: []string{"properties.spec.platform.subnetId"},
+		},
+		{
+			name: "Node pool name without its cluster name fails",
+			resources: []string{
+				testPreflightCluster("myCluster"),
+				strings.Replace(testPreflightNodePool, `"name": "myCluster/myNodePool"`, `"name": "myNodePool"`, 1),
+			},
+			failedResource:  "myNodePool",
+			expectedTargets: []string{"name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := csfake.NewServer()
+			defer cs.Close()
+
+			version, err := cmv1.NewVersion().
+				ID("openshift-v4.16.0").
+				RawID("4.16.0").
+				Enabled(true).
+				HostedControlPlaneEnabled(true).
+				Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = cs.AddVersion(version); err != nil {
+				t.Fatal(err)
+			}
+
+			_, ts := newTestFrontend(t, cs, subscriptionID)
+
+			for _, name := range tt.existingClusters {
+				rs := doRequest(t, ts, http.MethodPut, clustersPath+name, testClusterBody, nil)
+				if rs.StatusCode != http.StatusCreated {
+					t.Fatalf("create %s: expected status code %d, got %d", name, http.StatusCreated, rs.StatusCode)
+				}
+			}
+			for _, name := range tt.existingNodePools {
+				rs := doRequest(t, ts, http.MethodPut, clustersPath+"myCluster/nodePools/"+name, testNodePoolBody, nil)
+				if rs.StatusCode != http.StatusCreated {
+					t.Fatalf("create %s: expected status code %d, got %d", name, http.StatusCreated, rs.StatusCode)
+				}
+			}
+			for _, name := range tt.deletedResources {
+				rs := doRequest(t, ts, http.MethodDelete, clustersPath+name, "", nil)
+				if rs.StatusCode != http.StatusAccepted {
+					t.Fatalf("delete %s: expected status code %d, got %d", name, http.StatusAccepted, rs.StatusCode)
+				}
+			}
+
+			var response arm.DeploymentPreflightResponse
+			rs := doRequest(t, ts, http.MethodPost, preflightPath, `{"resources": [`+strings.Join(tt.resources, ",")+`]}`, &response)
+			if rs.StatusCode != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+			}
+
+			if len(tt.expectedTargets) == 0 {
+				if response.Status != arm.DeploymentPreflightStatusSucceeded {
+					t.Errorf("expected status %q, got %q: %+v", arm.DeploymentPreflightStatusSucceeded, response.Status, response.Error)
+				}
+				return
+			}
+
+			if response.Status != arm.DeploymentPreflightStatusFailed || response.Error == nil {
+				t.Fatalf("expected status %q with an error, got %q", arm.DeploymentPreflightStatusFailed, response.Status)
+			}
+			failedResource := tt.failedResource
+			if failedResource == "" {
+				failedResource = "myCluster"
+			}
+			if !strings.HasSuffix(response.Error.Target, "/"+failedResource) {
+				t.Errorf("expected error target to be %s, got %q", failedResource, response.Error.Target)
+			}
+			if len(response.Error.Details) != len(tt.expectedTargets) {
+				t.Fatalf("expected %d error details, got %+v", len(tt.expectedTargets), response.Error.Details)
+			}
+			for i, target := range tt.expectedTargets {
+				if response.Error.Details[i].Target != target {
+					t.Errorf("expected error target %q, got %q: %s", target, response.Error.Details[i].Target, response.Error.Details[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestArmDeploymentPreflightUnsupportedType(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const preflightPath = "/subscriptions/" + subscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/deployments/myDeployment/preflight"
+
+	cs := csfake.NewServer()
+	defer cs.Close()
+
+	_, ts := newTestFrontend(t, cs, subscriptionID)
+
+	var response arm.DeploymentPreflightResponse
+	rs := doRequest(t, ts, http.MethodPost, preflightPath, `{"resources": [
+		{"name": "myVNet", "type": "Microsoft.Network/virtualNetworks", "location": "eastus", "apiVersion": "2023-09-01"},
+		{"name": "myThing", "type": "Microsoft.RedHatOpenShift/things", "location": "eastus", "apiVersion": "2024-06-10-preview"}
+	]}`, &response)
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+
+	if response.Status != arm.DeploymentPreflightStatusFailed || response.Error == nil {
+		t.Fatalf("expected status %q with an error, got %q", arm.DeploymentPreflightStatusFailed, response.Status)
+	}
+	if response.Error.Code != arm.CloudErrorCodeInvalidResourceType {
+		t.Errorf("expected error code %q, got %q", arm.CloudErrorCodeInvalidResourceType, response.Error.Code)
+	}
+	if !strings.HasSuffix(response.Error.Target, "/things/myThing") {
+		t.Errorf("expected error target to be the unsupported resource, got %q", response.Error.Target)
+	}
+}
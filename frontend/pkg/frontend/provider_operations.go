@@ -0,0 +1,172 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// providerResourceType describes a resource type served under the
+// provider namespace, relative to the namespace.
+type providerResourceType struct {
+	Name    string
+	Display string
+}
+
+// providerResourceTypes lists the resource types to include in the
+// provider operations list. Routes under the provider namespace for
+// resource types not listed here, such as ARM deployment preflight,
+// are omitted from the list.
+var providerResourceTypes = []providerResourceType{
+	{
+		Name:    "operations",
+		Display: "Operations",
+	},
+	{
+		Name:    strings.TrimPrefix(api.ResourceType, api.ProviderNamespace+"/"),
+		Display: api.ResourceTypeDisplay,
+	},
+	{
+		Name:    strings.TrimPrefix(api.NodePoolResourceType, api.ProviderNamespace+"/"),
+		Display: api.NodePoolResourceTypeDisplay,
+	},
+	{
+		Name:    path.Join("locations", api.VersionResourceTypeName),
+		Display: api.VersionResourceTypeDisplay,
+	},
+	{
+		Name:    path.Join("locations", api.OperationStatusResourceTypeName),
+		Display: "Operation Status",
+	},
+	{
+		Name:    path.Join("locations", api.OperationResultResourceTypeName),
+		Display: "Operation Results",
+	},
+}
+
+// buildProviderOperations derives the provider operations list from mux
+// patterns, so the list always reflects the routes actually served.
+// Literal path segments following the provider namespace identify the
+// resource type and the HTTP method identifies the RBAC verb. Action
+// routes expand to one operation per entry in clusterActions.
+func buildProviderOperations(patterns []string) []arm.Operation {
+	var operations []arm.Operation
+
+	prefix := strings.ToLower(path.Join("providers", api.ProviderNamespace)) + "/"
+
+	add := func(resourceType providerResourceType, verb, operation, description string) {
+		name := path.Join(api.ProviderNamespace, resourceType.Name, verb)
+		if slices.ContainsFunc(operations, func(o arm.Operation) bool { return o.Name == name }) {
+			return
+		}
+		operations = append(operations, arm.Operation{
+			Name: name,
+			Display: &arm.OperationDisplay{
+				Provider:    api.ProviderNamespaceDisplay,
+				Resource:    resourceType.Display,
+				Operation:   operation,
+				Description: description,
+			},
+			Origin: arm.OriginUserSystem,
+		})
+	}
+
+	for _, pattern := range patterns {
+		method, urlPath, found := strings.Cut(pattern, " ")
+		if !found {
+			continue
+		}
+		_, urlPath, found = strings.Cut(urlPath, prefix)
+		if !found {
+			continue
+		}
+
+		var literals []string
+		var isAction bool
+		for _, segment := range strings.Split(urlPath, "/") {
+			switch {
+			case segment == PatternActionName:
+				isAction = true
+			case strings.HasPrefix(segment, "{"):
+			default:
+				literals = append(literals, segment)
+			}
+		}
+
+		index := slices.IndexFunc(providerResourceTypes, func(t providerResourceType) bool {
+			return strings.EqualFold(t.Name, strings.Join(literals, "/"))
+		})
+		if index < 0 {
+			continue
+		}
+		resourceType := providerResourceTypes[index]
+
+		switch method {
+		case http.MethodGet:
+			add(resourceType, "read",
+				"Read "+resourceType.Display,
+				"Gets or lists "+resourceType.Display+".")
+		case http.MethodPut, http.MethodPatch:
+			add(resourceType, "write",
+				"Create or Update "+resourceType.Display,
+				"Creates or updates "+resourceType.Display+".")
+		case http.MethodDelete:
+			add(resourceType, "delete",
+				"Delete "+resourceType.Display,
+				"Deletes "+resourceType.Display+".")
+		case http.MethodPost:
+			if !isAction {
+				continue
+			}
+			for actionName := range clusterActions {
+				add(resourceType, path.Join(actionName, "action"),
+					fmt.Sprintf("Perform %s action", actionName),
+					fmt.Sprintf("Performs the %s action on %s.", actionName, resourceType.Display))
+			}
+		}
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Name < operations[j].Name
+	})
+
+	return operations
+}
+
+// ArmProviderOperationList serves the list of operations supported by
+// the resource provider.
+// See https://github.com/cloud-and-ai-microsoft/resource-provider-contract/blob/master/v1.0/proxy-api-reference.md#exposing-available-operations
+func (f *Frontend) ArmProviderOperationList(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmProviderOperationList", versionedInterface))
+
+	resp, err := json.Marshal(arm.OperationList{Value: f.providerOperations})
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
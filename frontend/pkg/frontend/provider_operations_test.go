@@ -0,0 +1,70 @@
+package frontend
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+func TestArmProviderOperationList(t *testing.T) {
+	f := &Frontend{
+		dbClient: database.NewCache(),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:  NewPrometheusEmitter(),
+		region:   "eastus",
+	}
+
+	ts := newTestServer(t, f)
+
+	rs, err := ts.Client().Get(ts.URL + "/providers/Microsoft.RedHatOpenShift/operations?api-version=2024-06-10-preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+
+	var result generated.OperationListResult
+	if err = json.NewDecoder(rs.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make(map[string]bool)
+	for _, operation := range result.Value {
+		if operation.Name == nil || operation.Display == nil {
+			t.Fatalf("operation is missing a name or display: %+v", operation)
+		}
+		actual[*operation.Name] = true
+	}
+
+	expected := []string{
+		"Microsoft.RedHatOpenShift/operations/read",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/read",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/write",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/delete",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/adminCredentials/action",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/kubeConfig/action",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools/read",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools/write",
+		"Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools/delete",
+		"Microsoft.RedHatOpenShift/locations/hcpOpenShiftVersions/read",
+		"Microsoft.RedHatOpenShift/locations/hcpOperationsStatus/read",
+		"Microsoft.RedHatOpenShift/locations/hcpOperationResults/read",
+	}
+
+	for _, name := range expected {
+		if !actual[name] {
+			t.Errorf("expected operation %q", name)
+		}
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("expected %d operations, got %d", len(expected), len(actual))
+	}
+}
@@ -63,6 +63,37 @@ func (f *Frontend) routes() *MiddlewareMux {
 	mux.Handle(
 		MuxPattern(http.MethodPost, PatternSubscriptions, PatternResourceGroups, PatternProviders, PatternResourceName, PatternActionName),
 		postMuxMiddleware.HandlerFunc(f.ArmResourceAction))
+	mux.Handle(
+		MuxPattern(http.MethodGet, PatternSubscriptions, PatternResourceGroups, PatternProviders, PatternResourceName, api.NodePoolResourceTypeName),
+		postMuxMiddleware.HandlerFunc(f.ArmNodePoolList))
+	mux.Handle(
+		MuxPattern(http.MethodGet, PatternSubscriptions, PatternResourceGroups, PatternProviders, PatternResourceName, PatternNodepoolResource),
+		postMuxMiddleware.HandlerFunc(f.ArmNodePoolRead))
+	mux.Handle(
+		MuxPattern(http.MethodPut, PatternSubscriptions, PatternResourceGroups, PatternProviders, PatternResourceName, PatternNodepoolResource),
+		postMuxMiddleware.HandlerFunc(f.ArmNodePoolCreateOrUpdate))
+	mux.Handle(
+		MuxPattern(http.MethodPatch, PatternSubscriptions, PatternResourceGroups, PatternProviders, PatternResourceName, PatternNodepoolResource),
+		postMuxMiddleware.HandlerFunc(f.ArmNodePoolCreateOrUpdate))
+	mux.Handle(
+		MuxPattern(http.MethodDelete, PatternSubscriptions, PatternResourceGroups, PatternProviders, PatternResourceName, PatternNodepoolResource),
+		postMuxMiddleware.HandlerFunc(f.ArmNodePoolDelete))
+	mux.Handle(
+		MuxPattern(http.MethodGet, PatternSubscriptions, "providers", api.ProviderNamespace, PatternLocations, PatternOperationsStatus),
+		postMuxMiddleware.HandlerFunc(f.OperationStatus))
+	mux.Handle(
+		MuxPattern(http.MethodGet, PatternSubscriptions, "providers", api.ProviderNamespace, PatternLocations, PatternOperationResults),
+		postMuxMiddleware.HandlerFunc(f.OperationResult))
+	mux.Handle(
+		MuxPattern(http.MethodGet, PatternSubscriptions, "providers", api.ProviderNamespace, PatternLocations, api.VersionResourceTypeName),
+		postMuxMiddleware.HandlerFunc(f.ArmVersionList))
+
+	// Provider-scoped routes have no subscription to validate.
+	mux.Handle(
+		MuxPattern(http.MethodGet, "providers", api.ProviderNamespace, "operations"),
+		NewMiddleware(
+			MiddlewareLoggingPostMux,
+			MiddlewareValidateAPIVersion).HandlerFunc(f.ArmProviderOperationList))
 
 	// Exclude ARO-HCP API version validation for endpoints defined by ARM.
 	postMuxMiddleware = NewMiddleware(
@@ -72,5 +103,7 @@ func (f *Frontend) routes() *MiddlewareMux {
 		MuxPattern(http.MethodPost, PatternSubscriptions, PatternResourceGroups, "providers", api.ProviderNamespace, PatternDeployments, "preflight"),
 		postMuxMiddleware.HandlerFunc(f.ArmDeploymentPreflight))
 
+	f.providerOperations = buildProviderOperations(mux.Patterns())
+
 	return mux
 }
@@ -0,0 +1,35 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+)
+
+// patchFields returns the top-level fields of a PATCH request body, or
+// nil if the body is not a JSON object.
+func patchFields(body []byte) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// isTagsOnlyPatch returns true if a PATCH request body changes nothing
+// but the resource tags. Tags are not stored in Cluster Service, so such
+// a request can be completed without contacting it.
+func isTagsOnlyPatch(body []byte) bool {
+	fields := patchFields(body)
+	_, ok := fields["tags"]
+	return ok && len(fields) == 1
+}
+
+// patchReplacesTags returns true if a PATCH request body sets the resource
+// tags. ARM replaces the tags as a whole rather than merging them, so the
+// current tags must not be carried into the request.
+func patchReplacesTags(body []byte) bool {
+	_, ok := patchFields(body)["tags"]
+	return ok
+}
@@ -0,0 +1,73 @@
+package frontend
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// ArmVersionList lists the OpenShift versions available for installing
+// a cluster in a location. Versions are served from Cluster Service.
+func (f *Frontend) ArmVersionList(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmVersionList", versionedInterface))
+
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+	location := request.PathValue(PageSegmentLocation)
+
+	pageSize, token, cloudError := parseListParameters(request)
+	if cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+	pageNumber := max(token.Page, 1)
+
+	csVersions, total, err := f.clusterServiceClient.ListCSVersions(ctx, csVersionsSearch, pageNumber, pageSize)
+	if err != nil {
+		f.writeClusterServiceError(writer, request, fmt.Errorf("failed to list versions from clusters-service: %w", err))
+		return
+	}
+
+	result := api.VersionedHCPOpenShiftVersionList{
+		Value: make([]*api.VersionedHCPOpenShiftVersion, 0, len(csVersions)),
+	}
+
+	for _, csVersion := range csVersions {
+		hcpVersion := f.ConvertCStoHCPOpenShiftVersion(subscriptionID, location, csVersion)
+		versionedResource := versionedInterface.NewHCPOpenShiftVersion(hcpVersion)
+		result.Value = append(result.Value, &versionedResource)
+	}
+
+	// Check if there are more pages to fetch and set NextLink if applicable.
+	if pageNumber*pageSize < total {
+		link := nextLink(request, skipToken{Page: pageNumber + 1})
+		result.NextLink = &link
+	}
+
+	resp, err := json.Marshal(result)
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer)
+		return
+	}
+
+	_, err = writer.Write(resp)
+	if err != nil {
+		f.logger.Error(err.Error())
+	}
+}
@@ -0,0 +1,134 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+
+	"github.com/Azure/ARO-HCP/frontend/pkg/database"
+	"github.com/Azure/ARO-HCP/frontend/pkg/ocm"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestArmVersionList(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const versionsPath = "/subscriptions/" + subscriptionID + "/providers/Microsoft.RedHatOpenShift/locations/eastus/hcpOpenShiftVersions"
+
+	var csSearch, csPage string
+	cs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/clusters_mgmt/v1/versions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		csSearch = r.URL.Query().Get("search")
+		csPage = r.URL.Query().Get("page")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"kind": "VersionList",
+			"page": 1,
+			"size": 2,
+			"total": 3,
+			"items": [
+				{"kind": "Version", "id": "openshift-v4.16.0", "raw_id": "4.16.0", "enabled": true},
+				{"kind": "Version", "id": "openshift-v4.16.1", "raw_id": "4.16.1", "enabled": true}
+			]
+		}`))
+	}))
+	defer cs.Close()
+
+	conn, err := sdk.NewUnauthenticatedConnectionBuilder().URL(cs.URL).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	f := &Frontend{
+		clusterServiceClient: ocm.NewClusterServiceClient(conn),
+		dbClient:             database.NewCache(),
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:              NewPrometheusEmitter(),
+		region:               "eastus",
+	}
+
+	err = f.dbClient.SetSubscriptionDoc(context.TODO(), &database.SubscriptionDocument{
+		PartitionKey: subscriptionID,
+		Subscription: &arm.Subscription{State: arm.Registered},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, f)
+
+	// ARM passes the URL it received in the Referer header.
+	requestURL := ts.URL + versionsPath + "?api-version=2024-06-10-preview&$top=2"
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Referer", requestURL)
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+
+	if !strings.Contains(csSearch, "hosted_control_plane_enabled") {
+		t.Errorf("expected search to filter hosted control plane versions, got %q", csSearch)
+	}
+
+	var body struct {
+		Value []struct {
+			ID         string `json:"id"`
+			Name       string `json:"name"`
+			Properties struct {
+				ClusterVersion string `json:"clusterVersion"`
+			} `json:"properties"`
+		}
+		NextLink *string
+	}
+	if err = json.NewDecoder(rs.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Value) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(body.Value))
+	}
+	if body.Value[0].Properties.ClusterVersion != "4.16.0" {
+		t.Errorf("expected cluster version %q, got %q", "4.16.0", body.Value[0].Properties.ClusterVersion)
+	}
+	if !strings.EqualFold(body.Value[0].ID, versionsPath+"/4.16.0") {
+		t.Errorf("unexpected resource ID %q", body.Value[0].ID)
+	}
+	if body.NextLink == nil || !strings.HasPrefix(*body.NextLink, ts.URL+versionsPath+"?") {
+		t.Fatalf("expected an absolute next link, got %v", body.NextLink)
+	}
+	if strings.Contains(*body.NextLink, "page=") {
+		t.Errorf("expected the page to be encoded in an opaque skip token, got %s", *body.NextLink)
+	}
+
+	rs, err = ts.Client().Get(*body.NextLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("next page: expected status code %d, got %d", http.StatusOK, rs.StatusCode)
+	}
+	if csPage != "2" {
+		t.Errorf("next page: expected Cluster Service page 2, got %q", csPage)
+	}
+}
@@ -0,0 +1,307 @@
+package ocm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+)
+
+var _ ClusterServiceClient = &MockClusterServiceClient{}
+
+// MockClusterServiceClient is an in-memory ClusterServiceClient for unit
+// tests. Search expressions are ignored, so list methods return every
+// stored item. Set Err to make every method fail with that error.
+type MockClusterServiceClient struct {
+	// Err, if set, is returned by every method
+	Err error
+
+	mu          sync.Mutex
+	clusters    map[string]*cmv1.Cluster
+	nodePools   map[string]map[string]*cmv1.NodePool
+	versions    []*cmv1.Version
+	credentials map[string]*cmv1.ClusterCredentials
+	admins      map[string]*cmv1.AdminCredentials
+}
+
+// NewMockClusterServiceClient returns an empty MockClusterServiceClient.
+func NewMockClusterServiceClient() *MockClusterServiceClient {
+	return &MockClusterServiceClient{
+		clusters:    make(map[string]*cmv1.Cluster),
+		nodePools:   make(map[string]map[string]*cmv1.NodePool),
+		credentials: make(map[string]*cmv1.ClusterCredentials),
+		admins:      make(map[string]*cmv1.AdminCredentials),
+	}
+}
+
+// SetCSClusterCredentials sets the credentials returned for a cluster.
+func (m *MockClusterServiceClient) SetCSClusterCredentials(clusterID string, credentials *cmv1.ClusterCredentials, admin *cmv1.AdminCredentials) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.credentials[clusterID] = credentials
+	m.admins[clusterID] = admin
+}
+
+// AddCSVersion makes a version available for listing.
+func (m *MockClusterServiceClient) AddCSVersion(version *cmv1.Version) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions = append(m.versions, version)
+}
+
+func (m *MockClusterServiceClient) GetCSCluster(ctx context.Context, clusterID string) (*cmv1.Cluster, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	cluster, ok := m.clusters[clusterID]
+	if !ok {
+		return nil, notFoundError("Cluster", clusterID)
+	}
+	return cluster, nil
+}
+
+func (m *MockClusterServiceClient) ListCSClusters(ctx context.Context, searchExpression string, page, size int) ([]*cmv1.Cluster, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, 0, m.Err
+	}
+	clusters := make([]*cmv1.Cluster, 0, len(m.clusters))
+	for _, id := range sortedKeys(m.clusters) {
+		clusters = append(clusters, m.clusters[id])
+	}
+	return paginate(clusters, page, size), len(clusters), nil
+}
+
+func (m *MockClusterServiceClient) PostCSCluster(ctx context.Context, cluster *cmv1.Cluster) (*cmv1.Cluster, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	cluster, err := cmv1.NewCluster().Copy(cluster).
+		ID(id).
+		HREF("/api/clusters_mgmt/v1/clusters/" + id).
+		State(cmv1.ClusterStatePending).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	m.clusters[id] = cluster
+	return cluster, nil
+}
+
+// UpdateCSCluster replaces the stored cluster's updatable attributes
+// with those set in cluster.
+func (m *MockClusterServiceClient) UpdateCSCluster(ctx context.Context, clusterID string, cluster *cmv1.Cluster) (*cmv1.Cluster, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	current, ok := m.clusters[clusterID]
+	if !ok {
+		return nil, notFoundError("Cluster", clusterID)
+	}
+	builder := cmv1.NewCluster().Copy(current)
+	if version, ok := cluster.GetVersion(); ok {
+		builder.Version(cmv1.NewVersion().Copy(current.Version()).ID(version.ID()))
+	}
+	if proxy, ok := cluster.GetProxy(); ok {
+		builder.Proxy(cmv1.NewProxy().Copy(proxy))
+	}
+	if trustBundle, ok := cluster.GetAdditionalTrustBundle(); ok {
+		builder.AdditionalTrustBundle(trustBundle)
+	}
+	if disabled, ok := cluster.GetDisableUserWorkloadMonitoring(); ok {
+		builder.DisableUserWorkloadMonitoring(disabled)
+	}
+	updated, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	m.clusters[clusterID] = updated
+	return updated, nil
+}
+
+// DeleteCSCluster removes the cluster and its node pools immediately.
+func (m *MockClusterServiceClient) DeleteCSCluster(ctx context.Context, clusterID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.clusters[clusterID]; !ok {
+		return notFoundError("Cluster", clusterID)
+	}
+	delete(m.clusters, clusterID)
+	delete(m.nodePools, clusterID)
+	return nil
+}
+
+func (m *MockClusterServiceClient) GetCSClusterCredentials(ctx context.Context, clusterID string) (*cmv1.ClusterCredentials, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if _, ok := m.clusters[clusterID]; !ok {
+		return nil, notFoundError("Cluster", clusterID)
+	}
+	if credentials, ok := m.credentials[clusterID]; ok && credentials != nil {
+		return credentials, nil
+	}
+	return cmv1.NewClusterCredentials().ID(clusterID).Build()
+}
+
+func (m *MockClusterServiceClient) GetCSClusterAdminCredentials(ctx context.Context, clusterID string) (*cmv1.AdminCredentials, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if _, ok := m.clusters[clusterID]; !ok {
+		return nil, notFoundError("Cluster", clusterID)
+	}
+	if admin, ok := m.admins[clusterID]; ok && admin != nil {
+		return admin, nil
+	}
+	return cmv1.NewAdminCredentials().Build()
+}
+
+func (m *MockClusterServiceClient) GetCSNodePool(ctx context.Context, clusterID, nodePoolID string) (*cmv1.NodePool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	nodePool, ok := m.nodePools[clusterID][nodePoolID]
+	if !ok {
+		return nil, notFoundError("Node pool", nodePoolID)
+	}
+	return nodePool, nil
+}
+
+func (m *MockClusterServiceClient) ListCSNodePools(ctx context.Context, clusterID string) ([]*cmv1.NodePool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if _, ok := m.clusters[clusterID]; !ok {
+		return nil, notFoundError("Cluster", clusterID)
+	}
+	nodePools := make([]*cmv1.NodePool, 0, len(m.nodePools[clusterID]))
+	for _, id := range sortedKeys(m.nodePools[clusterID]) {
+		nodePools = append(nodePools, m.nodePools[clusterID][id])
+	}
+	return nodePools, nil
+}
+
+func (m *MockClusterServiceClient) PostCSNodePool(ctx context.Context, clusterID string, nodePool *cmv1.NodePool) (*cmv1.NodePool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if _, ok := m.clusters[clusterID]; !ok {
+		return nil, notFoundError("Cluster", clusterID)
+	}
+	if m.nodePools[clusterID] == nil {
+		m.nodePools[clusterID] = make(map[string]*cmv1.NodePool)
+	}
+	m.nodePools[clusterID][nodePool.ID()] = nodePool
+	return nodePool, nil
+}
+
+// UpdateCSNodePool replaces the stored node pool's updatable attributes
+// with those set in nodePool.
+func (m *MockClusterServiceClient) UpdateCSNodePool(ctx context.Context, clusterID, nodePoolID string, nodePool *cmv1.NodePool) (*cmv1.NodePool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	current, ok := m.nodePools[clusterID][nodePoolID]
+	if !ok {
+		return nil, notFoundError("Node pool", nodePoolID)
+	}
+	builder := cmv1.NewNodePool().Copy(current)
+	if replicas, ok := nodePool.GetReplicas(); ok {
+		builder.Replicas(replicas)
+	}
+	if autoscaling, ok := nodePool.GetAutoscaling(); ok {
+		builder.Autoscaling(cmv1.NewNodePoolAutoscaling().Copy(autoscaling))
+	}
+	if labels, ok := nodePool.GetLabels(); ok {
+		builder.Labels(labels)
+	}
+	updated, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	m.nodePools[clusterID][nodePoolID] = updated
+	return updated, nil
+}
+
+func (m *MockClusterServiceClient) DeleteCSNodePool(ctx context.Context, clusterID, nodePoolID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.nodePools[clusterID][nodePoolID]; !ok {
+		return notFoundError("Node pool", nodePoolID)
+	}
+	delete(m.nodePools[clusterID], nodePoolID)
+	return nil
+}
+
+func (m *MockClusterServiceClient) ListCSVersions(ctx context.Context, searchExpression string, page, size int) ([]*cmv1.Version, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, 0, m.Err
+	}
+	return paginate(m.versions, page, size), len(m.versions), nil
+}
+
+// notFoundError mimics the error Cluster Service returns for a missing
+// resource.
+func notFoundError(kind, id string) error {
+	err, _ := ocmerrors.NewError().
+		Status(http.StatusNotFound).
+		ID(fmt.Sprint(http.StatusNotFound)).
+		Code(fmt.Sprintf("CLUSTERS-MGMT-%d", http.StatusNotFound)).
+		Reason(fmt.Sprintf("%s '%s' not found", kind, id)).
+		Build()
+	return err
+}
+
+// paginate returns the given 1-based page of items.
+func paginate[T any](items []T, page, size int) []T {
+	start := min(max(page-1, 0)*size, len(items))
+	end := min(start+size, len(items))
+	return items[start:end]
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
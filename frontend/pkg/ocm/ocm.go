@@ -0,0 +1,213 @@
+// Package ocm wraps the Cluster Service clusters_mgmt v1 API behind an
+// interface so callers do not depend on the SDK connection directly.
+package ocm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+)
+
+// ClusterServiceClient covers the Cluster Service operations used by the
+// frontend and backend. List methods return one page of items along with
+// the total number of items matching the search expression.
+type ClusterServiceClient interface {
+	GetCSCluster(ctx context.Context, clusterID string) (*cmv1.Cluster, error)
+	ListCSClusters(ctx context.Context, searchExpression string, page, size int) ([]*cmv1.Cluster, int, error)
+	PostCSCluster(ctx context.Context, cluster *cmv1.Cluster) (*cmv1.Cluster, error)
+	UpdateCSCluster(ctx context.Context, clusterID string, cluster *cmv1.Cluster) (*cmv1.Cluster, error)
+	DeleteCSCluster(ctx context.Context, clusterID string) error
+
+	GetCSClusterCredentials(ctx context.Context, clusterID string) (*cmv1.ClusterCredentials, error)
+	GetCSClusterAdminCredentials(ctx context.Context, clusterID string) (*cmv1.AdminCredentials, error)
+
+	GetCSNodePool(ctx context.Context, clusterID, nodePoolID string) (*cmv1.NodePool, error)
+	ListCSNodePools(ctx context.Context, clusterID string) ([]*cmv1.NodePool, error)
+	PostCSNodePool(ctx context.Context, clusterID string, nodePool *cmv1.NodePool) (*cmv1.NodePool, error)
+	UpdateCSNodePool(ctx context.Context, clusterID, nodePoolID string, nodePool *cmv1.NodePool) (*cmv1.NodePool, error)
+	DeleteCSNodePool(ctx context.Context, clusterID, nodePoolID string) error
+
+	ListCSVersions(ctx context.Context, searchExpression string, page, size int) ([]*cmv1.Version, int, error)
+}
+
+// IsNotFound returns true if err is a Cluster Service 404 response.
+func IsNotFound(err error) bool {
+	var ocmError *ocmerrors.Error
+	return errors.As(err, &ocmError) && ocmError.Status() == http.StatusNotFound
+}
+
+var _ ClusterServiceClient = &ClusterServiceClientSpec{}
+
+// ClusterServiceClientSpec implements ClusterServiceClient with an
+// ocm-sdk-go connection. Errors returned by Cluster Service are
+// *ocmerrors.Error values.
+type ClusterServiceClientSpec struct {
+	// Conn is an ocm-sdk-go connection to Cluster Service
+	Conn *sdk.Connection
+}
+
+// NewClusterServiceClient returns a ClusterServiceClient using conn.
+func NewClusterServiceClient(conn *sdk.Connection) ClusterServiceClient {
+	return &ClusterServiceClientSpec{Conn: conn}
+}
+
+// GetCSCluster creates and sends a GET request to fetch a cluster from Clusters Service
+func (c *ClusterServiceClientSpec) GetCSCluster(ctx context.Context, clusterID string) (*cmv1.Cluster, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).Get().SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// ListCSClusters creates and sends a GET request to fetch a page of clusters from Clusters Service
+func (c *ClusterServiceClientSpec) ListCSClusters(ctx context.Context, searchExpression string, page, size int) ([]*cmv1.Cluster, int, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().List().
+		Search(searchExpression).
+		Page(page).
+		Size(size).
+		SendContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Items().Slice(), resp.Total(), nil
+}
+
+// PostCSCluster creates and sends a POST request to create a cluster in Clusters Service
+func (c *ClusterServiceClientSpec) PostCSCluster(ctx context.Context, cluster *cmv1.Cluster) (*cmv1.Cluster, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Add().Body(cluster).SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// UpdateCSCluster creates and sends a PATCH request to update a cluster in Clusters Service
+func (c *ClusterServiceClientSpec) UpdateCSCluster(ctx context.Context, clusterID string, cluster *cmv1.Cluster) (*cmv1.Cluster, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).Update().Body(cluster).SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// DeleteCSCluster creates and sends a DELETE request to delete a cluster from Clusters Service
+func (c *ClusterServiceClientSpec) DeleteCSCluster(ctx context.Context, clusterID string) error {
+	_, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).Delete().SendContext(ctx)
+	return err
+}
+
+// GetCSClusterCredentials creates and sends a GET request to fetch a cluster's credentials from Clusters Service
+func (c *ClusterServiceClientSpec) GetCSClusterCredentials(ctx context.Context, clusterID string) (*cmv1.ClusterCredentials, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).Credentials().Get().SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// GetCSClusterAdminCredentials creates and sends a GET request to fetch a cluster's
+// kubeadmin credentials from Clusters Service. The SDK's ClusterCredentials type
+// omits the "admin" attribute of the credentials endpoint, so the response body
+// is decoded here.
+func (c *ClusterServiceClientSpec) GetCSClusterAdminCredentials(ctx context.Context, clusterID string) (*cmv1.AdminCredentials, error) {
+	resp, err := c.Conn.Get().
+		Path(path.Join("/api/clusters_mgmt/v1/clusters", clusterID, "credentials")).
+		SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status() >= http.StatusBadRequest {
+		ocmError, err := ocmerrors.UnmarshalErrorStatus(resp.Bytes(), resp.Status())
+		if err != nil {
+			return nil, err
+		}
+		return nil, ocmError
+	}
+
+	var body struct {
+		Admin json.RawMessage `json:"admin"`
+	}
+	if err = json.Unmarshal(resp.Bytes(), &body); err != nil {
+		return nil, err
+	}
+	if len(body.Admin) == 0 {
+		return cmv1.NewAdminCredentials().Build()
+	}
+	return cmv1.UnmarshalAdminCredentials([]byte(body.Admin))
+}
+
+// GetCSNodePool creates and sends a GET request to fetch a node pool from Clusters Service
+func (c *ClusterServiceClientSpec) GetCSNodePool(ctx context.Context, clusterID, nodePoolID string) (*cmv1.NodePool, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).NodePools().NodePool(nodePoolID).Get().SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// ListCSNodePools creates and sends GET requests to list all of a cluster's node pools from Clusters Service
+func (c *ClusterServiceClientSpec) ListCSNodePools(ctx context.Context, clusterID string) ([]*cmv1.NodePool, error) {
+	var nodePools []*cmv1.NodePool
+
+	request := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).NodePools().List()
+	for page := 1; ; page++ {
+		resp, err := request.Page(page).SendContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodePools = append(nodePools, resp.Items().Slice()...)
+		if len(nodePools) >= resp.Total() || resp.Size() == 0 {
+			break
+		}
+	}
+
+	return nodePools, nil
+}
+
+// PostCSNodePool creates and sends a POST request to create a node pool in Clusters Service
+func (c *ClusterServiceClientSpec) PostCSNodePool(ctx context.Context, clusterID string, nodePool *cmv1.NodePool) (*cmv1.NodePool, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).NodePools().Add().Body(nodePool).SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// UpdateCSNodePool creates and sends a PATCH request to update a node pool in Clusters Service
+func (c *ClusterServiceClientSpec) UpdateCSNodePool(ctx context.Context, clusterID, nodePoolID string, nodePool *cmv1.NodePool) (*cmv1.NodePool, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).NodePools().NodePool(nodePoolID).Update().Body(nodePool).SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+// DeleteCSNodePool creates and sends a DELETE request to delete a node pool from Clusters Service
+func (c *ClusterServiceClientSpec) DeleteCSNodePool(ctx context.Context, clusterID, nodePoolID string) error {
+	_, err := c.Conn.ClustersMgmt().V1().Clusters().Cluster(clusterID).NodePools().NodePool(nodePoolID).Delete().SendContext(ctx)
+	return err
+}
+
+// ListCSVersions creates and sends a GET request to fetch a page of versions from Clusters Service
+func (c *ClusterServiceClientSpec) ListCSVersions(ctx context.Context, searchExpression string, page, size int) ([]*cmv1.Version, int, error) {
+	resp, err := c.Conn.ClustersMgmt().V1().Versions().List().
+		Search(searchExpression).
+		Order("id asc").
+		Page(page).
+		Size(size).
+		SendContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Items().Slice(), resp.Total(), nil
+}
@@ -11,6 +11,7 @@ import (
 
 // CloudError codes
 const (
+	CloudErrorCodeConflict               = "Conflict"
 	CloudErrorCodeInternalServerError    = "InternalServerError"
 	CloudErrorCodeInvalidParameter       = "InvalidParameter"
 	CloudErrorCodeInvalidRequestContent  = "InvalidRequestContent"
@@ -19,12 +20,16 @@ const (
 	CloudErrorCodeMultipleErrorsOccurred = "MultipleErrorsOccurred"
 	CloudErrorCodeUnsupportedMediaType   = "UnsupportedMediaType"
 	CloudErrorCodeNotFound               = "NotFound"
+	CloudErrorCodePreconditionFailed     = "PreconditionFailed"
 	CloudErrorInvalidSubscriptionState   = "InvalidSubscriptionState"
 	CloudErrorCodeResourceNotFound       = "ResourceNotFound"
 	CloudErrorCodeResourceGroupNotFound  = "ResourceGroupNotFound"
 	CloudErrorCodeInvalidSubscriptionID  = "InvalidSubscriptionID"
 	CloudErrorInvalidResourceName        = "InvalidResourceName"
 	CloudErrorInvalidResourceGroupName   = "InvalidResourceGroupName"
+	CloudErrorCodeTooManyRequests        = "TooManyRequests"
+	CloudErrorCodeServiceUnavailable     = "ServiceUnavailable"
+	CloudErrorCodeFeatureNotRegistered   = "SubscriptionNotRegisteredForFeature"
 )
 
 // CloudError represents a complete resource provider error.
@@ -11,4 +11,12 @@ const (
 	HeaderNameCorrelationRequestID  = "X-Ms-Correlation-Request-Id"
 	HeaderNameReturnClientRequestID = "X-Ms-Return-Client-Request-Id"
 	HeaderNameARMResourceSystemData = "X-Ms-Arm-Resource-System-Data"
+	HeaderNameAsyncOperation        = "Azure-AsyncOperation"
+
+	// Standard HTTP header names
+	HeaderNameETag        = "ETag"
+	HeaderNameIfMatch     = "If-Match"
+	HeaderNameIfNoneMatch = "If-None-Match"
+	HeaderNameLocation    = "Location"
+	HeaderNameRetryAfter  = "Retry-After"
 )
@@ -0,0 +1,40 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// ManagedServiceIdentityType is the type of managed identity assigned to a resource
+type ManagedServiceIdentityType string
+
+const (
+	ManagedServiceIdentityTypeNone                       ManagedServiceIdentityType = "None"
+	ManagedServiceIdentityTypeSystemAssigned             ManagedServiceIdentityType = "SystemAssigned"
+	ManagedServiceIdentityTypeSystemAssignedUserAssigned ManagedServiceIdentityType = "SystemAssigned,UserAssigned"
+	ManagedServiceIdentityTypeUserAssigned               ManagedServiceIdentityType = "UserAssigned"
+)
+
+// HasUserAssigned returns true if the type includes user-assigned identities.
+func (t ManagedServiceIdentityType) HasUserAssigned() bool {
+	switch t {
+	case ManagedServiceIdentityTypeUserAssigned, ManagedServiceIdentityTypeSystemAssignedUserAssigned:
+		return true
+	default:
+		return false
+	}
+}
+
+// ManagedServiceIdentity represents the managed identities assigned to a resource
+// See https://github.com/Azure/azure-resource-manager-rpc/blob/master/v1.0/managed-identity.md
+type ManagedServiceIdentity struct {
+	Type        ManagedServiceIdentityType `json:"type,omitempty"        validate:"required,enum_managedserviceidentitytype"`
+	PrincipalID string                     `json:"principalId,omitempty" visibility:"read"`
+	TenantID    string                     `json:"tenantId,omitempty"    visibility:"read"`
+	// UserAssignedIdentities is keyed by the ARM resource ID of each identity
+	UserAssignedIdentities map[string]*UserAssignedIdentity `json:"userAssignedIdentities,omitempty" validate:"omitempty,dive,keys,resource_id=Microsoft.ManagedIdentity/userAssignedIdentities,endkeys"`
+}
+
+// UserAssignedIdentity holds the identifiers of a user-assigned identity
+type UserAssignedIdentity struct {
+	ClientID    string `json:"clientId,omitempty"    visibility:"read"`
+	PrincipalID string `json:"principalId,omitempty" visibility:"read"`
+}
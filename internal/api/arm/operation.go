@@ -0,0 +1,70 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"time"
+)
+
+// OperationStatus represents the status of an asynchronous operation as
+// returned by the Azure-AsyncOperation endpoint.
+// See https://github.com/cloud-and-ai-microsoft/resource-provider-contract/blob/master/v1.0/async-api-reference.md#azure-asyncoperation-resource-format
+type OperationStatus struct {
+	// ID is the fully qualified ID of the operation status resource
+	ID string `json:"id,omitempty"`
+	// Name is the operation ID
+	Name string `json:"name,omitempty"`
+	// Status is the provisioning state of the operation
+	Status ProvisioningState `json:"status"`
+	// StartTime is the UTC date and time at which the operation started
+	StartTime *time.Time `json:"startTime,omitempty"`
+	// EndTime is the UTC date and time at which the operation reached a terminal state
+	EndTime *time.Time `json:"endTime,omitempty"`
+	// PercentComplete is the progress of the operation, between 0 and 100
+	PercentComplete float64 `json:"percentComplete,omitempty"`
+	// Error describes the reason for a Failed or Canceled operation
+	Error *CloudErrorBody `json:"error,omitempty"`
+}
+
+// Origin is the intended executor of an operation.
+type Origin string
+
+const (
+	OriginUser       Origin = "user"
+	OriginSystem     Origin = "system"
+	OriginUserSystem Origin = "user,system"
+)
+
+// Operation represents a REST API operation supported by a resource
+// provider, as returned by the provider operations endpoint.
+// See https://github.com/cloud-and-ai-microsoft/resource-provider-contract/blob/master/v1.0/proxy-api-reference.md#exposing-available-operations
+type Operation struct {
+	// Name is the operation name as per Resource-Based Access Control (RBAC)
+	Name string `json:"name"`
+	// IsDataAction is true for data-plane operations
+	IsDataAction bool `json:"isDataAction"`
+	// Display is localized display information for the operation
+	Display *OperationDisplay `json:"display,omitempty"`
+	// Origin is the intended executor of the operation
+	Origin Origin `json:"origin,omitempty"`
+}
+
+// OperationDisplay is localized display information for an Operation.
+type OperationDisplay struct {
+	// Provider is the friendly name of the resource provider
+	Provider string `json:"provider,omitempty"`
+	// Resource is the friendly name of the resource type
+	Resource string `json:"resource,omitempty"`
+	// Operation is the concise, friendly name of the operation
+	Operation string `json:"operation,omitempty"`
+	// Description is the friendly description of the operation
+	Description string `json:"description,omitempty"`
+}
+
+// OperationList is a list of operations supported by a resource provider.
+type OperationList struct {
+	Value []Operation `json:"value"`
+	// NextLink is the URL to get the next set of results
+	NextLink string `json:"nextLink,omitempty"`
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"path"
+	"strings"
 )
 
 // See https://learn.microsoft.com/en-us/rest/api/datareplication/deployment-preflight/deployment-preflight?view=rest-datareplication-2021-02-16-preview&tabs=Go
@@ -42,9 +43,24 @@ type DeploymentPreflightResource struct {
 	APIVersion string `json:"apiVersion" validate:"required,api_version"`
 }
 
-// ResourceID returns a resource ID string for the resource.
+// ResourceID returns a resource ID string for the resource. The name of a
+// child resource includes the names of its parents, such as "parent/child",
+// which are interleaved with the resource type names.
 func (r *DeploymentPreflightResource) ResourceID(subscriptionID, resourceGroup string) string {
-	return path.Join("/subscriptions", subscriptionID, "resourcegroups", resourceGroup, "providers", r.Type, r.Name)
+	segments := []string{"/subscriptions", subscriptionID, "resourcegroups", resourceGroup, "providers"}
+
+	namespace, types, _ := strings.Cut(r.Type, "/")
+	typeNames := strings.Split(types, "/")
+	names := strings.Split(r.Name, "/")
+	if types == "" || len(typeNames) != len(names) {
+		return path.Join(append(segments, r.Type, r.Name)...)
+	}
+
+	segments = append(segments, namespace)
+	for i := range typeNames {
+		segments = append(segments, typeNames[i], names[i])
+	}
+	return path.Join(segments...)
 }
 
 // DeploymentPreflightStatus is used in a DeploymentPreflightResponse.
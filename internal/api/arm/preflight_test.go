@@ -0,0 +1,53 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"testing"
+)
+
+func TestDeploymentPreflightResourceID(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+	const resourceGroup = "myRG"
+
+	tests := []struct {
+		name     string
+		resource DeploymentPreflightResource
+		want     string
+	}{
+		{
+			name: "Top-level resource",
+			resource: DeploymentPreflightResource{
+				Name: "myCluster",
+				Type: "Microsoft.RedHatOpenShift/hcpOpenShiftClusters",
+			},
+			want: "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster",
+		},
+		{
+			name: "Child resource",
+			resource: DeploymentPreflightResource{
+				Name: "myCluster/myNodePool",
+				Type: "Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools",
+			},
+			want: "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster/nodePools/myNodePool",
+		},
+		{
+			name: "Child resource without parent name",
+			resource: DeploymentPreflightResource{
+				Name: "myNodePool",
+				Type: "Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools",
+			},
+			want: "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools/myNodePool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.resource.ResourceID(subscriptionID, resourceGroup)
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -31,8 +31,10 @@ func (src *Resource) Copy(dst *Resource) {
 // TrackedResource represents a tracked ARM resource
 type TrackedResource struct {
 	Resource
-	Location string            `json:"location,omitempty"`
-	Tags     map[string]string `json:"tags,omitempty"`
+	Location string `json:"location,omitempty"`
+	// Tags are limited as described at
+	// https://learn.microsoft.com/azure/azure-resource-manager/management/tag-resources#limitations
+	Tags map[string]string `json:"tags,omitempty" validate:"max=50,dive,keys,required,max=512,excludesall=<>%&\\?/,endkeys,max=256"`
 }
 
 func (src *TrackedResource) Copy(dst *TrackedResource) {
@@ -103,3 +105,13 @@ const (
 	ProvisioningStateProvisioning ProvisioningState = "Provisioning"
 	ProvisioningStateUpdating     ProvisioningState = "Updating"
 )
+
+// IsTerminal returns true if the state is terminal.
+func (s ProvisioningState) IsTerminal() bool {
+	switch s {
+	case ProvisioningStateSucceeded, ProvisioningStateFailed, ProvisioningStateCanceled:
+		return true
+	default:
+		return false
+	}
+}
@@ -26,6 +26,9 @@ type Feature struct {
 	State *string `json:"state,omitempty"`
 }
 
+// FeatureStateRegistered is the state of a feature the subscription can use.
+const FeatureStateRegistered = "Registered"
+
 type AvailabilityZone struct {
 	Location     *string        `json:"location,omitempty"`
 	ZoneMappings *[]ZoneMapping `json:"zoneMppings,omitempty"`
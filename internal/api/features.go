@@ -0,0 +1,88 @@
+package api
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// Preview features a subscription must register through Azure Feature
+// Exposure Control (AFEC) to use the capabilities they gate.
+const (
+	FeatureFIPS       = ProviderNamespace + "/FIPS"
+	FeaturePrivateAPI = ProviderNamespace + "/PrivateAPI"
+)
+
+// FeatureSet is the set of preview features registered to a subscription.
+// Feature names are case-insensitive. A nil FeatureSet has no features.
+type FeatureSet map[string]struct{}
+
+// NewFeatureSet returns the features registered to a subscription.
+func NewFeatureSet(subscription *arm.Subscription) FeatureSet {
+	features := FeatureSet{}
+	if subscription == nil || subscription.Properties == nil || subscription.Properties.RegisteredFeatures == nil {
+		return features
+	}
+	for _, feature := range *subscription.Properties.RegisteredFeatures {
+		if feature.Name == nil {
+			continue
+		}
+		if feature.State != nil && !strings.EqualFold(*feature.State, arm.FeatureStateRegistered) {
+			continue
+		}
+		features[strings.ToLower(*feature.Name)] = struct{}{}
+	}
+	return features
+}
+
+// Has returns true if the feature is registered.
+func (features FeatureSet) Has(feature string) bool {
+	_, ok := features[strings.ToLower(feature)]
+	return ok
+}
+
+// clusterFeatureGate ties a cluster capability to the preview feature
+// that enables it.
+type clusterFeatureGate struct {
+	feature string
+	field   string
+	target  string
+	uses    func(*HCPOpenShiftCluster) bool
+}
+
+var clusterFeatureGates = []clusterFeatureGate{
+	{
+		feature: FeatureFIPS,
+		field:   "fips",
+		target:  "properties.spec.fips",
+		uses:    func(c *HCPOpenShiftCluster) bool { return c.Properties.Spec.FIPS },
+	},
+	{
+		feature: FeaturePrivateAPI,
+		field:   "visibility",
+		target:  "properties.spec.api.visibility",
+		uses:    func(c *HCPOpenShiftCluster) bool { return c.Properties.Spec.API.Visibility == VisibilityPrivate },
+	},
+}
+
+// ValidateClusterFeatures returns an error for each capability used by
+// the cluster whose preview feature is not registered.
+func ValidateClusterFeatures(cluster *HCPOpenShiftCluster, features FeatureSet) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	for _, gate := range clusterFeatureGates {
+		if gate.uses(cluster) && !features.Has(gate.feature) {
+			errorDetails = append(errorDetails, arm.CloudErrorBody{
+				Code:    arm.CloudErrorCodeFeatureNotRegistered,
+				Message: fmt.Sprintf("Field '%s' requires the subscription to be registered for feature '%s'", gate.field, gate.feature),
+				Target:  gate.target,
+			})
+		}
+	}
+
+	return errorDetails
+}
@@ -0,0 +1,78 @@
+package api
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestValidateClusterFeatures(t *testing.T) {
+	subscription := &arm.Subscription{
+		State: arm.Registered,
+		Properties: &arm.Properties{
+			RegisteredFeatures: &[]arm.Feature{
+				{Name: Ptr("microsoft.redhatopenshift/fips"), State: Ptr("Registered")},
+				{Name: Ptr(FeaturePrivateAPI), State: Ptr("Pending")},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		fips           bool
+		visibility     Visibility
+		features       FeatureSet
+		expectedTarget string
+	}{
+		{
+			name:       "No gated fields",
+			visibility: VisibilityPublic,
+		},
+		{
+			name:           "FIPS without feature",
+			fips:           true,
+			visibility:     VisibilityPublic,
+			expectedTarget: "properties.spec.fips",
+		},
+		{
+			name:       "FIPS with feature",
+			fips:       true,
+			visibility: VisibilityPublic,
+			features:   NewFeatureSet(subscription),
+		},
+		{
+			name:           "Private API with pending feature",
+			visibility:     VisibilityPrivate,
+			features:       NewFeatureSet(subscription),
+			expectedTarget: "properties.spec.api.visibility",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := NewDefaultHCPOpenShiftCluster()
+			cluster.Properties.Spec.FIPS = test.fips
+			cluster.Properties.Spec.API.Visibility = test.visibility
+
+			errorDetails := ValidateClusterFeatures(cluster, test.features)
+			if test.expectedTarget == "" {
+				if len(errorDetails) != 0 {
+					t.Errorf("expected no errors, got %v", errorDetails)
+				}
+				return
+			}
+			if len(errorDetails) != 1 {
+				t.Fatalf("expected 1 error, got %d", len(errorDetails))
+			}
+			if errorDetails[0].Code != arm.CloudErrorCodeFeatureNotRegistered {
+				t.Errorf("expected code %q, got %q", arm.CloudErrorCodeFeatureNotRegistered, errorDetails[0].Code)
+			}
+			if errorDetails[0].Target != test.expectedTarget {
+				t.Errorf("expected target %q, got %q", test.expectedTarget, errorDetails[0].Target)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ package api
 // Licensed under the Apache License 2.0.
 
 import (
+	"fmt"
+
 	configv1 "github.com/openshift/api/config/v1"
 
 	"github.com/Azure/ARO-HCP/internal/api/arm"
@@ -12,6 +14,10 @@ import (
 // HCPOpenShiftCluster represents an ARO HCP OpenShift cluster resource.
 type HCPOpenShiftCluster struct {
 	arm.TrackedResource
+	// ETag is taken from the stored resource document. It is never
+	// read from a request body; use the If-Match header instead.
+	ETag       string                        `json:"etag,omitempty"`
+	Identity   *arm.ManagedServiceIdentity   `json:"identity,omitempty"   visibility:"read create"`
 	Properties HCPOpenShiftClusterProperties `json:"properties,omitempty" validate:"required_for_put"`
 }
 
@@ -106,6 +112,21 @@ type IngressProfile struct {
 	Visibility Visibility `json:"visibility,omitempty" visibility:"read create" validate:"required_for_put,enum_visibility"`
 }
 
+// ValidateClusterIdentity returns an error if the cluster is given a
+// managed identity. Cluster Service cannot yet be told which identities
+// the cluster operators should authenticate with, so the only identity
+// type accepted is None.
+func ValidateClusterIdentity(cluster *HCPOpenShiftCluster) []arm.CloudErrorBody {
+	if cluster.Identity == nil || cluster.Identity.Type == arm.ManagedServiceIdentityTypeNone {
+		return nil
+	}
+	return []arm.CloudErrorBody{{
+		Code:    arm.CloudErrorCodeInvalidRequestContent,
+		Message: fmt.Sprintf("Managed identity type '%s' is not supported yet (omit 'identity' or set its type to 'None')", cluster.Identity.Type),
+		Target:  "identity.type",
+	}}
+}
+
 // Creates an HCPOpenShiftCluster with any non-zero default values.
 func NewDefaultHCPOpenShiftCluster() *HCPOpenShiftCluster {
 	return &HCPOpenShiftCluster{
@@ -0,0 +1,17 @@
+package api
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// HCPOpenShiftClusterAdminCredentials represents the response body of the
+// "adminCredentials" action on an ARO HCP OpenShift cluster.
+type HCPOpenShiftClusterAdminCredentials struct {
+	KubeadminUsername string `json:"kubeadminUsername,omitempty"`
+	KubeadminPassword string `json:"kubeadminPassword,omitempty"`
+}
+
+// HCPOpenShiftClusterKubeconfig represents the response body of the
+// "kubeConfig" action on an ARO HCP OpenShift cluster.
+type HCPOpenShiftClusterKubeconfig struct {
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+}
@@ -11,14 +11,17 @@ import (
 // OpenShift clusters.
 type HCPOpenShiftClusterNodePool struct {
 	arm.TrackedResource
-	Properties HCPOpenShiftClusterNodePoolProperties `json:":properties,omitempty" validate:"required_for_put"`
+	// ETag is taken from the stored resource document. It is never
+	// read from a request body; use the If-Match header instead.
+	ETag       string                                `json:"etag,omitempty"`
+	Properties HCPOpenShiftClusterNodePoolProperties `json:"properties,omitempty" validate:"required_for_put"`
 }
 
 // HCPOpenShiftClusterNodePoolProperties represents the property bag of a
 // HCPOpenShiftClusterNodePool resource.
 type HCPOpenShiftClusterNodePoolProperties struct {
-	ProvisioningState arm.ProvisioningState `json:"provisioningState,omitempty" visibility:"read" validate:"omitempty,enum_provisioningstate"`
-	Spec              NodePoolSpec          `json:"spec,omitempty" visibility:"read,create,update" validate:"required_for_put"`
+	ProvisioningState arm.ProvisioningState `json:"provisioningState,omitempty" visibility:"read"               validate:"omitempty,enum_provisioningstate"`
+	Spec              NodePoolSpec          `json:"spec,omitempty"              visibility:"read create update" validate:"required_for_put"`
 }
 
 type NodePoolSpec struct {
@@ -26,7 +29,7 @@ type NodePoolSpec struct {
 	Platform      NodePoolPlatformProfile `json:"platform,omitempty" visibility:"read create" validate:"required_for_put"`
 	Replicas      int32                   `json:"replicas,omitempty" visibility:"read create update"`
 	AutoRepair    bool                    `json:"autoRepair,omitempty" visibility:"read create"`
-	Autoscaling   NodePoolAutoscaling     `json:"autoscaling,omitempty" visibility:"read create update"`
+	Autoscaling   NodePoolAutoscaling     `json:"autoScaling,omitempty" visibility:"read create update"`
 	Labels        map[string]string       `json:"labels,omitempty" visibility:"read create update"`
 	Taints        []*Taint                `json:"taints,omitempty" visibility:"read create update"`
 	TuningConfigs []string                `json:"tuningConfigs,omitempty" visibility:"read create update"`
@@ -46,10 +49,10 @@ type NodePoolPlatformProfile struct {
 }
 
 // NodePoolAutoscaling represents a node pool autoscaling configuration.
-// Visibility for the entire struct is "read".
+// Visibility for the entire struct is "read create update".
 type NodePoolAutoscaling struct {
-	Min int32 `json:"min,omitempty" validate:"required_for_put"`
-	Max int32 `json:"max,omitempty" validate:"required_for_put"`
+	Min int32 `json:"min,omitempty"`
+	Max int32 `json:"max,omitempty" validate:"omitempty,gtefield=Min"`
 }
 
 type Taint struct {
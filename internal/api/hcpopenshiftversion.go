@@ -0,0 +1,21 @@
+package api
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// HCPOpenShiftVersion represents a location-scoped OpenShift version
+// available for installing an ARO HCP OpenShift cluster.
+type HCPOpenShiftVersion struct {
+	arm.Resource
+	Properties HCPOpenShiftVersionProperties `json:"properties,omitempty"`
+}
+
+// HCPOpenShiftVersionProperties represents the property bag of a HCPOpenShiftVersion resource.
+type HCPOpenShiftVersionProperties struct {
+	ProvisioningState arm.ProvisioningState `json:"provisioningState,omitempty" visibility:"read"`
+	ClusterVersion    string                `json:"clusterVersion,omitempty"    visibility:"read"`
+}
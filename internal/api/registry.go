@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strings"
 
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	validator "github.com/go-playground/validator/v10"
 
 	"github.com/Azure/ARO-HCP/internal/api/arm"
@@ -19,25 +20,65 @@ const (
 	ProviderNamespaceDisplay = "Azure Red Hat OpenShift"
 	ResourceType             = ProviderNamespace + "/" + "hcpOpenShiftClusters"
 	ResourceTypeDisplay      = "Hosted Control Plane (HCP) OpenShift Clusters"
+
+	NodePoolResourceTypeName    = "nodePools"
+	NodePoolResourceType        = ResourceType + "/" + NodePoolResourceTypeName
+	NodePoolResourceTypeDisplay = "Hosted Control Plane (HCP) OpenShift Cluster Node Pools"
+
+	VersionResourceTypeName    = "hcpOpenShiftVersions"
+	VersionResourceType        = ProviderNamespace + "/" + VersionResourceTypeName
+	VersionResourceTypeDisplay = "Hosted Control Plane (HCP) OpenShift Versions"
+
+	// Action names for POST requests on a cluster resource
+	AdminCredentialsActionName = "adminCredentials"
+	KubeconfigActionName       = "kubeConfig"
+
+	// Location-scoped resource type names for tracking asynchronous operations
+	OperationStatusResourceTypeName = "hcpOperationsStatus"
+	OperationResultResourceTypeName = "hcpOperationResults"
 )
 
 type VersionedHCPOpenShiftCluster interface {
 	Normalize(*HCPOpenShiftCluster)
-	ValidateStatic(current VersionedHCPOpenShiftCluster, updating bool, method string) *arm.CloudError
+	ValidateStatic(current VersionedHCPOpenShiftCluster, updating bool, method string, features FeatureSet) *arm.CloudError
 }
 
 type VersionedHCPOpenShiftClusterList struct {
-	Value []*VersionedHCPOpenShiftCluster
+	Value []*VersionedHCPOpenShiftCluster `json:"value"`
 
 	// The link to the next page of items
-	NextLink *string
+	NextLink *string `json:"nextLink,omitempty"`
 }
 
 type VersionedHCPOpenShiftClusterNodePool interface {
 	Normalize(*HCPOpenShiftClusterNodePool)
-	ValidateStatic() *arm.CloudError
+	ValidateStatic(current VersionedHCPOpenShiftClusterNodePool, updating bool, method string) *arm.CloudError
+}
+
+type VersionedHCPOpenShiftClusterNodePoolList struct {
+	Value []*VersionedHCPOpenShiftClusterNodePool `json:"value"`
+
+	// The link to the next page of items
+	NextLink *string `json:"nextLink,omitempty"`
+}
+
+// VersionedHCPOpenShiftVersion is a read-only type and so needs
+// nothing beyond JSON marshaling.
+type VersionedHCPOpenShiftVersion interface{}
+
+type VersionedHCPOpenShiftVersionList struct {
+	Value []*VersionedHCPOpenShiftVersion `json:"value"`
+
+	// The link to the next page of items
+	NextLink *string `json:"nextLink,omitempty"`
 }
 
+// VersionedHCPOpenShiftClusterAdminCredentials and
+// VersionedHCPOpenShiftClusterKubeconfig are response-only types
+// and so need nothing beyond JSON marshaling.
+type VersionedHCPOpenShiftClusterAdminCredentials interface{}
+type VersionedHCPOpenShiftClusterKubeconfig interface{}
+
 type Version interface {
 	fmt.Stringer
 
@@ -45,6 +86,11 @@ type Version interface {
 	// Passing a nil pointer creates a resource with default values.
 	NewHCPOpenShiftCluster(*HCPOpenShiftCluster) VersionedHCPOpenShiftCluster
 	NewHCPOpenShiftClusterNodePool(*HCPOpenShiftClusterNodePool) VersionedHCPOpenShiftClusterNodePool
+	NewHCPOpenShiftVersion(*HCPOpenShiftVersion) VersionedHCPOpenShiftVersion
+
+	// Action Response Types
+	NewHCPOpenShiftClusterAdminCredentials(*HCPOpenShiftClusterAdminCredentials) VersionedHCPOpenShiftClusterAdminCredentials
+	NewHCPOpenShiftClusterKubeconfig(*HCPOpenShiftClusterKubeconfig) VersionedHCPOpenShiftClusterKubeconfig
 }
 
 // apiRegistry is the map of registered API versions
@@ -125,6 +171,36 @@ func NewValidator() *validator.Validate {
 		panic(err)
 	}
 
+	// Use this for string fields specifying an Azure resource ID.
+	// An optional parameter restricts the resource type.
+	err = validate.RegisterValidation("resource_id", func(fl validator.FieldLevel) bool {
+		field := fl.Field()
+		if field.Kind() != reflect.String {
+			panic("String type required for resource_id")
+		}
+		resourceID, err := azcorearm.ParseResourceID(field.String())
+		if err != nil {
+			return false
+		}
+		resourceType := fl.Param()
+		return resourceType == "" || strings.EqualFold(resourceID.ResourceType.String(), resourceType)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// User-assigned identities must be given if and only if
+	// the identity type includes them.
+	validate.RegisterStructValidation(func(sl validator.StructLevel) {
+		identity := sl.Current().Interface().(arm.ManagedServiceIdentity)
+		hasUserAssigned := len(identity.UserAssignedIdentities) > 0
+		if identity.Type.HasUserAssigned() && !hasUserAssigned {
+			sl.ReportError(identity.UserAssignedIdentities, "userAssignedIdentities", "UserAssignedIdentities", "required_for_identity_type", string(identity.Type))
+		} else if !identity.Type.HasUserAssigned() && hasUserAssigned {
+			sl.ReportError(identity.UserAssignedIdentities, "userAssignedIdentities", "UserAssignedIdentities", "excluded_for_identity_type", string(identity.Type))
+		}
+	}, arm.ManagedServiceIdentity{})
+
 	return validate
 }
 
@@ -147,7 +223,7 @@ func ValidateRequest(validate *validator.Validate, method string, resource any)
 	switch err := err.(type) {
 	case validator.ValidationErrors:
 		for _, fieldErr := range err {
-			message := fmt.Sprintf("Invalid value '%s' for field '%s'", fieldErr.Value(), fieldErr.Field())
+			message := fmt.Sprintf("Invalid value '%v' for field '%s'", fieldErr.Value(), fieldErr.Field())
 			// Try to add a corrective suggestion to the message.
 			tag := fieldErr.Tag()
 			if strings.HasPrefix(tag, "enum_") {
@@ -158,6 +234,26 @@ func ValidateRequest(validate *validator.Validate, method string, resource any)
 					message = fmt.Sprintf("Unrecognized API version '%s'", fieldErr.Value())
 				case "required", "required_for_put": // custom tag
 					message = fmt.Sprintf("Missing required field '%s'", fieldErr.Field())
+				case "required_for_identity_type": // custom tag
+					message = fmt.Sprintf("Missing required field '%s' for identity type '%s'", fieldErr.Field(), fieldErr.Param())
+				case "excluded_for_identity_type": // custom tag
+					message = fmt.Sprintf("Field '%s' is not allowed for identity type '%s'", fieldErr.Field(), fieldErr.Param())
+				case "resource_id": // custom tag
+					if fieldErr.Param() != "" {
+						message += fmt.Sprintf(" (must be a resource ID of type '%s')", fieldErr.Param())
+					} else {
+						message += " (must be a resource ID)"
+					}
+				case "max":
+					if fieldErr.Kind() == reflect.Map {
+						message = fmt.Sprintf("Too many entries in field '%s' (maximum is %s)", fieldErr.Field(), fieldErr.Param())
+					} else {
+						message += fmt.Sprintf(" (maximum length is %s)", fieldErr.Param())
+					}
+				case "excludesall":
+					message += fmt.Sprintf(" (must not contain any of: %s)", fieldErr.Param())
+				case "gtefield":
+					message += fmt.Sprintf(" (must be at least the value of '%s')", fieldErr.Param())
 				case "cidrv4":
 					message += " (must be a v4 CIDR address)"
 				case "ipv4":
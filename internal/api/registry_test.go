@@ -4,11 +4,15 @@ package api
 // Licensed under the Apache License 2.0.
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 
 	validator "github.com/go-playground/validator/v10"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
 
 func TestGetJSONTagName(t *testing.T) {
@@ -223,3 +227,170 @@ func TestNewValidator(t *testing.T) {
 		})
 	}
 }
+
+type TestIdentity struct {
+	Identity *arm.ManagedServiceIdentity `json:"identity"`
+}
+
+func TestValidateRequestIdentity(t *testing.T) {
+	const identityID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.ManagedIdentity/userAssignedIdentities/myIdentity"
+	const subnetID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRG/providers/Microsoft.Network/virtualNetworks/myVNet/subnets/mySubnet"
+
+	tests := []struct {
+		name           string
+		identity       *arm.ManagedServiceIdentity
+		expectedTarget string
+	}{
+		{
+			name: "No identity is ok",
+		},
+		{
+			name: "User-assigned identity is ok",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeUserAssigned,
+				UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+					identityID: {},
+				},
+			},
+		},
+		{
+			name: "Identity type with a comma is ok",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeSystemAssignedUserAssigned,
+				UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+					identityID: {},
+				},
+			},
+		},
+		{
+			name: "Unknown identity type is an error",
+			identity: &arm.ManagedServiceIdentity{
+				Type: "Bogus",
+			},
+			expectedTarget: "identity.type",
+		},
+		{
+			name: "User-assigned type without identities is an error",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeUserAssigned,
+			},
+			expectedTarget: "identity.userAssignedIdentities",
+		},
+		{
+			name: "System-assigned type with user-assigned identities is an error",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeSystemAssigned,
+				UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+					identityID: {},
+				},
+			},
+			expectedTarget: "identity.userAssignedIdentities",
+		},
+		{
+			name: "Malformed resource ID is an error",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeUserAssigned,
+				UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+					"myIdentity": {},
+				},
+			},
+			expectedTarget: "identity.userAssignedIdentities[myIdentity]",
+		},
+		{
+			name: "Wrong resource type is an error",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeUserAssigned,
+				UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+					subnetID: {},
+				},
+			},
+			expectedTarget: "identity.userAssignedIdentities[" + subnetID + "]",
+		},
+	}
+
+	validate := NewValidator()
+	validate.RegisterAlias("enum_managedserviceidentitytype", "oneof=None SystemAssigned SystemAssigned0x2CUserAssigned UserAssigned")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorDetails := ValidateRequest(validate, http.MethodPut, TestIdentity{Identity: tt.identity})
+			if tt.expectedTarget == "" {
+				if errorDetails != nil {
+					t.Errorf("Unexpected errors: %v", errorDetails)
+				}
+			} else if len(errorDetails) != 1 {
+				t.Errorf("Expected 1 error, got %v", errorDetails)
+			} else if errorDetails[0].Target != tt.expectedTarget {
+				t.Errorf("Expected error target '%s', got '%s': %s", tt.expectedTarget, errorDetails[0].Target, errorDetails[0].Message)
+			}
+		})
+	}
+}
+
+func TestValidateRequestTags(t *testing.T) {
+	tooManyTags := make(map[string]string)
+	for i := 0; i < 51; i++ {
+		tooManyTags[fmt.Sprintf("tag%d", i)] = "value"
+	}
+
+	tests := []struct {
+		name           string
+		tags           map[string]string
+		expectedTarget string
+	}{
+		{
+			name: "No tags is ok",
+		},
+		{
+			name: "Valid tags are ok",
+			tags: map[string]string{"environment": "production", "cost center": ""},
+		},
+		{
+			name:           "Too many tags is an error",
+			tags:           tooManyTags,
+			expectedTarget: "tags",
+		},
+		{
+			name:           "Empty tag name is an error",
+			tags:           map[string]string{"": "value"},
+			expectedTarget: "tags[]",
+		},
+		{
+			name:           "Long tag name is an error",
+			tags:           map[string]string{strings.Repeat("k", 513): "value"},
+			expectedTarget: "tags[" + strings.Repeat("k", 513) + "]",
+		},
+		{
+			name:           "Long tag value is an error",
+			tags:           map[string]string{"key": strings.Repeat("v", 257)},
+			expectedTarget: "tags[key]",
+		},
+		{
+			name:           "Forbidden character in tag name is an error",
+			tags:           map[string]string{"a/b": "value"},
+			expectedTarget: "tags[a/b]",
+		},
+		{
+			name:           "Backslash in tag name is an error",
+			tags:           map[string]string{`a\b`: "value"},
+			expectedTarget: `tags[a\b]`,
+		},
+	}
+
+	validate := NewValidator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorDetails := ValidateRequest(validate, http.MethodPut, arm.TrackedResource{Tags: tt.tags})
+			if tt.expectedTarget == "" {
+				if errorDetails != nil {
+					t.Errorf("Unexpected errors: %v", errorDetails)
+				}
+			} else if len(errorDetails) != 1 {
+				t.Errorf("Expected 1 error, got %v", errorDetails)
+			} else if errorDetails[0].Target != tt.expectedTarget {
+				t.Errorf("Expected error target '%s', got '%s': %s", tt.expectedTarget, errorDetails[0].Target, errorDetails[0].Message)
+			}
+		})
+	}
+}
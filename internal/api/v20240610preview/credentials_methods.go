@@ -0,0 +1,34 @@
+package v20240610preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+type HcpOpenShiftClusterCredentials struct {
+	generated.HcpOpenShiftClusterCredentials
+}
+
+type HcpOpenShiftClusterKubeconfig struct {
+	generated.HcpOpenShiftClusterKubeconfig
+}
+
+func (v version) NewHCPOpenShiftClusterAdminCredentials(from *api.HCPOpenShiftClusterAdminCredentials) api.VersionedHCPOpenShiftClusterAdminCredentials {
+	return &HcpOpenShiftClusterCredentials{
+		generated.HcpOpenShiftClusterCredentials{
+			KubeadminUsername: api.Ptr(from.KubeadminUsername),
+			KubeadminPassword: api.Ptr(from.KubeadminPassword),
+		},
+	}
+}
+
+func (v version) NewHCPOpenShiftClusterKubeconfig(from *api.HCPOpenShiftClusterKubeconfig) api.VersionedHCPOpenShiftClusterKubeconfig {
+	return &HcpOpenShiftClusterKubeconfig{
+		generated.HcpOpenShiftClusterKubeconfig{
+			Kubeconfig: api.Ptr(from.Kubeconfig),
+		},
+	}
+}
@@ -219,6 +219,9 @@ type HcpOpenShiftClusterNodePoolResource struct {
 	// Resource tags.
 	Tags map[string]*string
 
+	// READ-ONLY; Entity tag of the resource, which changes whenever the resource is modified
+	ETag *string
+
 	// READ-ONLY; Fully qualified resource ID for the resource. E.g. "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/{resourceProviderNamespace}/{resourceType}/{resourceName}"
 	ID *string
 
@@ -297,6 +300,9 @@ type HcpOpenShiftClusterResource struct {
 	// Resource tags.
 	Tags map[string]*string
 
+	// READ-ONLY; Entity tag of the resource, which changes whenever the resource is modified
+	ETag *string
+
 	// READ-ONLY; Fully qualified resource ID for the resource. E.g. "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/{resourceProviderNamespace}/{resourceType}/{resourceName}"
 	ID *string
 
@@ -613,6 +613,7 @@ func (h *HcpOpenShiftClusterKubeconfig) UnmarshalJSON(data []byte) error {
 // MarshalJSON implements the json.Marshaller interface for type HcpOpenShiftClusterNodePoolResource.
 func (h HcpOpenShiftClusterNodePoolResource) MarshalJSON() ([]byte, error) {
 	objectMap := make(map[string]any)
+	populate(objectMap, "etag", h.ETag)
 	populate(objectMap, "id", h.ID)
 	populate(objectMap, "location", h.Location)
 	populate(objectMap, "name", h.Name)
@@ -632,6 +633,9 @@ func (h *HcpOpenShiftClusterNodePoolResource) UnmarshalJSON(data []byte) error {
 	for key, val := range rawMsg {
 		var err error
 		switch key {
+		case "etag":
+				err = unpopulate(val, "ETag", &h.ETag)
+			delete(rawMsg, key)
 		case "id":
 				err = unpopulate(val, "ID", &h.ID)
 			delete(rawMsg, key)
@@ -814,6 +818,7 @@ func (h *HcpOpenShiftClusterProperties) UnmarshalJSON(data []byte) error {
 // MarshalJSON implements the json.Marshaller interface for type HcpOpenShiftClusterResource.
 func (h HcpOpenShiftClusterResource) MarshalJSON() ([]byte, error) {
 	objectMap := make(map[string]any)
+	populate(objectMap, "etag", h.ETag)
 	populate(objectMap, "id", h.ID)
 	populate(objectMap, "identity", h.Identity)
 	populate(objectMap, "location", h.Location)
@@ -834,6 +839,9 @@ func (h *HcpOpenShiftClusterResource) UnmarshalJSON(data []byte) error {
 	for key, val := range rawMsg {
 		var err error
 		switch key {
+		case "etag":
+				err = unpopulate(val, "ETag", &h.ETag)
+			delete(rawMsg, key)
 		case "id":
 				err = unpopulate(val, "ID", &h.ID)
 			delete(rawMsg, key)
@@ -17,6 +17,10 @@ type HcpOpenShiftClusterResource struct {
 	generated.HcpOpenShiftClusterResource
 }
 
+type ManagedServiceIdentity struct {
+	generated.ManagedServiceIdentity
+}
+
 type VersionProfile struct {
 	generated.VersionProfile
 }
@@ -53,6 +57,33 @@ type IngressProfile struct {
 	generated.IngressProfile
 }
 
+func newManagedServiceIdentity(from *arm.ManagedServiceIdentity) *generated.ManagedServiceIdentity {
+	out := &generated.ManagedServiceIdentity{
+		Type: api.Ptr(generated.ManagedServiceIdentityType(from.Type)),
+	}
+	if len(from.UserAssignedIdentities) > 0 {
+		out.UserAssignedIdentities = make(map[string]*generated.UserAssignedIdentity, len(from.UserAssignedIdentities))
+	}
+	if from.PrincipalID != "" {
+		out.PrincipalID = api.Ptr(from.PrincipalID)
+	}
+	if from.TenantID != "" {
+		out.TenantID = api.Ptr(from.TenantID)
+	}
+	for key, val := range from.UserAssignedIdentities {
+		out.UserAssignedIdentities[key] = &generated.UserAssignedIdentity{}
+		if val != nil {
+			if val.ClientID != "" {
+				out.UserAssignedIdentities[key].ClientID = api.Ptr(val.ClientID)
+			}
+			if val.PrincipalID != "" {
+				out.UserAssignedIdentities[key].PrincipalID = api.Ptr(val.PrincipalID)
+			}
+		}
+	}
+	return out
+}
+
 func newVersionProfile(from *api.VersionProfile) *generated.VersionProfile {
 	return &generated.VersionProfile{
 		ID:                api.Ptr(from.ID),
@@ -196,9 +227,7 @@ func (v version) NewHCPOpenShiftCluster(from *api.HCPOpenShiftCluster) api.Versi
 			Type:     api.Ptr(from.Resource.Type),
 			Location: api.Ptr(from.TrackedResource.Location),
 			Tags:     map[string]*string{},
-			// FIXME Skipping ManagedServiceIdentity
 			Properties: &generated.HcpOpenShiftClusterProperties{
-				ProvisioningState: api.Ptr(generated.ProvisioningState(from.Properties.ProvisioningState)),
 				Spec: &generated.ClusterSpec{
 					Version:                       newVersionProfile(&from.Properties.Spec.Version),
 					DNS:                           newDNSProfile(&from.Properties.Spec.DNS),
@@ -221,6 +250,20 @@ func (v version) NewHCPOpenShiftCluster(from *api.HCPOpenShiftCluster) api.Versi
 		},
 	}
 
+	if from.ETag != "" {
+		out.ETag = api.Ptr(from.ETag)
+	}
+
+	// A request body starts out from the defaults, which have no
+	// provisioning state to compare with the read-only current value.
+	if from.Properties.ProvisioningState != "" {
+		out.Properties.ProvisioningState = api.Ptr(generated.ProvisioningState(from.Properties.ProvisioningState))
+	}
+
+	if from.Identity != nil {
+		out.Identity = newManagedServiceIdentity(from.Identity)
+	}
+
 	if from.Resource.SystemData != nil {
 		out.SystemData = &generated.SystemData{
 			CreatedBy:          api.Ptr(from.Resource.SystemData.CreatedBy),
@@ -275,7 +318,10 @@ func (c *HcpOpenShiftClusterResource) Normalize(out *api.HCPOpenShiftCluster) {
 			out.Resource.SystemData.LastModifiedByType = arm.CreatedByType(*c.SystemData.LastModifiedByType)
 		}
 	}
-	// FIXME Skipping ManagedServiceIdentity
+	if c.Identity != nil {
+		out.Identity = &arm.ManagedServiceIdentity{}
+		normalizeIdentity(c.Identity, out.Identity)
+	}
 	if c.Location != nil {
 		out.TrackedResource.Location = *c.Location
 	}
@@ -336,7 +382,7 @@ func (c *HcpOpenShiftClusterResource) Normalize(out *api.HCPOpenShiftCluster) {
 	}
 }
 
-func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpenShiftCluster, updating bool, method string) *arm.CloudError {
+func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpenShiftCluster, updating bool, method string, features api.FeatureSet) *arm.CloudError {
 	var normalized api.HCPOpenShiftCluster
 	var errorDetails []arm.CloudErrorBody
 
@@ -363,6 +409,21 @@ func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpe
 		cloudError.Details = append(cloudError.Details, errorDetails...)
 	}
 
+	errorDetails = api.ValidateClusterIdentity(&normalized)
+	if errorDetails != nil {
+		cloudError.Details = append(cloudError.Details, errorDetails...)
+	}
+
+	// Gated fields cannot be updated, so only check features on
+	// creation. Existing clusters keep working if a feature is
+	// later unregistered.
+	if !updating {
+		errorDetails = api.ValidateClusterFeatures(&normalized, features)
+		if errorDetails != nil {
+			cloudError.Details = append(cloudError.Details, errorDetails...)
+		}
+	}
+
 	switch len(cloudError.Details) {
 	case 0:
 		cloudError = nil
@@ -374,6 +435,35 @@ func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpe
 	return cloudError
 }
 
+func (i *ManagedServiceIdentity) Normalize(out *arm.ManagedServiceIdentity) {
+	normalizeIdentity(&i.ManagedServiceIdentity, out)
+}
+
+func normalizeIdentity(p *generated.ManagedServiceIdentity, out *arm.ManagedServiceIdentity) {
+	if p.Type != nil {
+		out.Type = arm.ManagedServiceIdentityType(*p.Type)
+	}
+	if p.PrincipalID != nil {
+		out.PrincipalID = *p.PrincipalID
+	}
+	if p.TenantID != nil {
+		out.TenantID = *p.TenantID
+	}
+	out.UserAssignedIdentities = make(map[string]*arm.UserAssignedIdentity, len(p.UserAssignedIdentities))
+	for key, val := range p.UserAssignedIdentities {
+		// Values may be empty objects or null in requests.
+		out.UserAssignedIdentities[key] = &arm.UserAssignedIdentity{}
+		if val != nil {
+			if val.ClientID != nil {
+				out.UserAssignedIdentities[key].ClientID = *val.ClientID
+			}
+			if val.PrincipalID != nil {
+				out.UserAssignedIdentities[key].PrincipalID = *val.PrincipalID
+			}
+		}
+	}
+}
+
 func (p *VersionProfile) Normalize(out *api.VersionProfile) {
 	normalizeVersion(&p.VersionProfile, out)
 }
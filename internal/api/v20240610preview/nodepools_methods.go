@@ -1,6 +1,8 @@
 package v20240610preview
 
 import (
+	"net/http"
+
 	"github.com/Azure/ARO-HCP/internal/api"
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
@@ -61,41 +63,38 @@ func (h *HcpOpenShiftClusterNodePoolResource) Normalize(out *api.HCPOpenShiftClu
 			if h.Properties.Spec.Replicas != nil {
 				out.Properties.Spec.Replicas = *h.Properties.Spec.Replicas
 			}
-		}
-		if h.Properties.Spec.Platform != nil {
-			normalizeNodePoolPlatform(h.Properties.Spec.Platform, &out.Properties.Spec.Platform)
-		}
-		if h.Properties.Spec.AutoScaling != nil {
-			if h.Properties.Spec.AutoScaling.Max != nil {
-				out.Properties.Spec.Autoscaling.Max = *h.Properties.Spec.AutoScaling.Max
-			}
-			if h.Properties.Spec.AutoScaling.Min != nil {
-				out.Properties.Spec.Autoscaling.Min = *h.Properties.Spec.AutoScaling.Min
+			if h.Properties.Spec.Platform != nil {
+				normalizeNodePoolPlatform(h.Properties.Spec.Platform, &out.Properties.Spec.Platform)
 			}
-		}
-		out.Properties.Spec.Labels = make(map[string]string)
-		for k, v := range h.Properties.Spec.Labels {
-			if v != nil {
-				out.Properties.Spec.Labels[k] = *v
+			if h.Properties.Spec.AutoScaling != nil {
+				if h.Properties.Spec.AutoScaling.Max != nil {
+					out.Properties.Spec.Autoscaling.Max = *h.Properties.Spec.AutoScaling.Max
+				}
+				if h.Properties.Spec.AutoScaling.Min != nil {
+					out.Properties.Spec.Autoscaling.Min = *h.Properties.Spec.AutoScaling.Min
+				}
 			}
-		}
-		out.Properties.Spec.Taints = make([]*api.Taint, len(h.Properties.Spec.Taints))
-		for i := range h.Properties.Spec.Taints {
-			out.Properties.Spec.Taints[i] = &api.Taint{}
-			if h.Properties.Spec.Taints[i].Effect != nil {
-				out.Properties.Spec.Taints[i].Effect = api.Effect(*h.Properties.Spec.Taints[i].Effect)
+			out.Properties.Spec.Labels = make(map[string]string)
+			for k, v := range h.Properties.Spec.Labels {
+				if v != nil {
+					out.Properties.Spec.Labels[k] = *v
+				}
 			}
-			if h.Properties.Spec.Taints[i].Key != nil {
-				out.Properties.Spec.Taints[i].Key = *h.Properties.Spec.Taints[i].Key
+			taintSequence := api.DeleteNilsFromPtrSlice(h.Properties.Spec.Taints)
+			out.Properties.Spec.Taints = make([]*api.Taint, len(taintSequence))
+			for i, taint := range taintSequence {
+				out.Properties.Spec.Taints[i] = &api.Taint{}
+				if taint.Effect != nil {
+					out.Properties.Spec.Taints[i].Effect = api.Effect(*taint.Effect)
+				}
+				if taint.Key != nil {
+					out.Properties.Spec.Taints[i].Key = *taint.Key
+				}
+				if taint.Value != nil {
+					out.Properties.Spec.Taints[i].Value = *taint.Value
+				}
 			}
-			if h.Properties.Spec.Taints[i].Value != nil {
-				out.Properties.Spec.Taints[i].Value = *h.Properties.Spec.Taints[i].Value
-			}
-		}
-
-		out.Properties.Spec.TuningConfigs = make([]string, len(h.Properties.Spec.TuningConfigs))
-		for i := range h.Properties.Spec.TuningConfigs {
-			out.Properties.Spec.TuningConfigs[i] = *h.Properties.Spec.TuningConfigs[i]
+			out.Properties.Spec.TuningConfigs = api.StringPtrSliceToStringSlice(h.Properties.Spec.TuningConfigs)
 		}
 	}
 }
@@ -128,9 +127,42 @@ func normalizeNodePoolPlatform(p *generated.NodePoolPlatformProfile, out *api.No
 
 }
 
-func (h *HcpOpenShiftClusterNodePoolResource) ValidateStatic() *arm.CloudError {
-	//TODO implement me
-	return nil
+func (h *HcpOpenShiftClusterNodePoolResource) ValidateStatic(current api.VersionedHCPOpenShiftClusterNodePool, updating bool, method string) *arm.CloudError {
+	var normalized api.HCPOpenShiftClusterNodePool
+	var errorDetails []arm.CloudErrorBody
+
+	cloudError := arm.NewCloudError(
+		http.StatusBadRequest,
+		arm.CloudErrorCodeMultipleErrorsOccurred, "",
+		"Content validation failed on multiple fields")
+	cloudError.Details = make([]arm.CloudErrorBody, 0)
+
+	// Pass the embedded HcpOpenShiftClusterNodePoolResource so the
+	// struct field names match the nodePoolStructTagMap keys.
+	errorDetails = api.ValidateVisibility(
+		h.HcpOpenShiftClusterNodePoolResource,
+		current.(*HcpOpenShiftClusterNodePoolResource).HcpOpenShiftClusterNodePoolResource,
+		nodePoolStructTagMap, updating)
+	if errorDetails != nil {
+		cloudError.Details = append(cloudError.Details, errorDetails...)
+	}
+
+	h.Normalize(&normalized)
+
+	errorDetails = api.ValidateRequest(validate, method, &normalized)
+	if errorDetails != nil {
+		cloudError.Details = append(cloudError.Details, errorDetails...)
+	}
+
+	switch len(cloudError.Details) {
+	case 0:
+		cloudError = nil
+	case 1:
+		// Promote a single validation error out of details.
+		cloudError.CloudErrorBody = &cloudError.Details[0]
+	}
+
+	return cloudError
 }
 
 type NodePoolPlatformProfile struct {
@@ -182,7 +214,6 @@ func (v version) NewHCPOpenShiftClusterNodePool(from *api.HCPOpenShiftClusterNod
 			Location: api.Ptr(from.Location),
 			Tags:     map[string]*string{},
 			Properties: &generated.NodePoolProperties{
-				ProvisioningState: api.Ptr(generated.ProvisioningState(from.Properties.ProvisioningState)),
 				Spec: &generated.NodePoolSpec{
 					Platform:      newNodePoolPlatformProfile(&from.Properties.Spec.Platform),
 					Version:       newVersionProfile(&from.Properties.Spec.Version),
@@ -197,6 +228,16 @@ func (v version) NewHCPOpenShiftClusterNodePool(from *api.HCPOpenShiftClusterNod
 		},
 	}
 
+	if from.ETag != "" {
+		out.ETag = api.Ptr(from.ETag)
+	}
+
+	// A request body starts out from the defaults, which have no
+	// provisioning state to compare with the read-only current value.
+	if from.Properties.ProvisioningState != "" {
+		out.Properties.ProvisioningState = api.Ptr(generated.ProvisioningState(from.Properties.ProvisioningState))
+	}
+
 	if from.Resource.SystemData != nil {
 		out.SystemData = &generated.SystemData{
 			CreatedBy:          api.Ptr(from.Resource.SystemData.CreatedBy),
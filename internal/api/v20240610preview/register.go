@@ -19,14 +19,17 @@ func (v version) String() string {
 }
 
 var (
-	validate            = api.NewValidator()
-	clusterStructTagMap = api.NewStructTagMap[api.HCPOpenShiftCluster]()
+	validate             = api.NewValidator()
+	clusterStructTagMap  = api.NewStructTagMap[api.HCPOpenShiftCluster]()
+	nodePoolStructTagMap = api.NewStructTagMap[api.HCPOpenShiftClusterNodePool]()
 )
 
 func EnumValidateTag[S ~string](values ...S) string {
 	s := make([]string, len(values))
 	for i, e := range values {
-		s[i] = string(e)
+		// Commas separate validation tags, so escape any
+		// within a value such as "SystemAssigned,UserAssigned".
+		s[i] = strings.ReplaceAll(string(e), ",", "0x2C")
 	}
 	return fmt.Sprintf("oneof=%s", strings.Join(s, " "))
 }
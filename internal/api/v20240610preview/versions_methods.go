@@ -0,0 +1,27 @@
+package v20240610preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+type HcpOpenShiftVersions struct {
+	generated.HcpOpenShiftVersions
+}
+
+func (v version) NewHCPOpenShiftVersion(from *api.HCPOpenShiftVersion) api.VersionedHCPOpenShiftVersion {
+	return &HcpOpenShiftVersions{
+		generated.HcpOpenShiftVersions{
+			ID:   api.Ptr(from.Resource.ID),
+			Name: api.Ptr(from.Resource.Name),
+			Type: api.Ptr(from.Resource.Type),
+			Properties: &generated.HcpOpenShiftVersionsProperties{
+				ClusterVersion:    api.Ptr(from.Properties.ClusterVersion),
+				ProvisioningState: api.Ptr(generated.ResourceProvisioningState(from.Properties.ProvisioningState)),
+			},
+		},
+	}
+}